@@ -99,6 +99,8 @@ func bindStruct(v reflect.Value, c *Config, path string) (err error) {
 			}
 		case reflect.Array, reflect.Slice:
 			c.Globals.RepeatedVar(long, short, help, v.Field(i).Addr().Interface())
+		case reflect.Map:
+			c.Globals.KeyValueVar(long, short, help, v.Field(i).Addr().Interface())
 		case reflect.Struct:
 			if err = bindStruct(v.Field(i), c, long); err != nil {
 				return
@@ -11,6 +11,7 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"slices"
 )
 
 var (
@@ -23,6 +24,17 @@ const (
 	DefaultLongPrefix = "--"
 	// DefaultShortPrefix is the default prefix for short option names.
 	DefaultShortPrefix = "-"
+	// DefaultTerminator is the default options terminator.
+	DefaultTerminator = "--"
+	// DefaultKeySeparator is the default separator of keys and values of
+	// KeyValue options.
+	DefaultKeySeparator = "="
+	// DefaultAritySeparator is the default separator of values of an option
+	// with an Arity greater than one given in a single argument.
+	DefaultAritySeparator = ","
+	// NegationPrefix is the long name prefix that negates a Negatable
+	// Boolean option, e.g. '--no-color'.
+	NegationPrefix = "no-"
 )
 
 // defaultOutput is the default output [Config] writes text output to.
@@ -54,23 +66,33 @@ type Config struct {
 
 	// Globals are the global Options, independant of any defined commands.
 	//
-	// They are parsed from arguments that precede any command invocation.
+	// They are parsed from arguments that precede any command invocation
+	// unless they are Persistent or [Config.GlobalsAnywhere] is enabled.
 	// Their state can be inspected either from [Config.GlobalsHandler] or
 	// after parsing by inspecting the Globals directly.
 	Globals Options
 
 	// GlobalsHandler is an optional handler for Globals.
 	//
-	// It is invoked if any global options get parsed and before any command
-	// handlers are invoked.
+	// It is invoked after Globals have been parsed and validated and before
+	// any commands are parsed. If any of the Globals are Persistent or
+	// [Config.GlobalsAnywhere] is enabled Globals may be specified in
+	// arguments of commands so it is invoked after all arguments have been
+	// parsed and before any command handlers are invoked instead. This is
+	// also the case if [Config.CollectErrors] is enabled so that no handler
+	// is invoked if any error occured.
 	//
-	// If it returns an error no commands will be parsed and the error
-	// propagated back to the caller.
+	// If it returns an error no commands will be parsed or no command
+	// handlers invoked, respectively, and the error is propagated back to the
+	// caller.
 	GlobalsHandler Handler
 
 	// GlobalExclusivityGroups are exclusivity groups for Globals.
 	GlobalExclusivityGroups ExclusivityGroups
 
+	// GlobalConstraints are dependency constraints for Globals.
+	GlobalConstraints Constraints
+
 	// Commands is the root command set.
 	Commands Commands
 
@@ -91,6 +113,53 @@ type Config struct {
 	// Default: false
 	IndexedFirst bool
 
+	// ResponseFiles enables expansion of response files in [Config.Args]
+	// and specifies how they are read.
+	//
+	// If enabled, an argument prefixed with [ResponseFilePrefix] names a file
+	// whose contents are read as arguments in its place, e.g. '@args.txt'.
+	// Response files may reference other response files up to a nesting
+	// depth of [MaxResponseFileDepth]. A response file may not reference
+	// itself, directly or indirectly.
+	//
+	// Errors caused by arguments read from a response file are prefixed with
+	// the file name and line number of the argument.
+	//
+	// Default: NoResponseFiles
+	ResponseFiles ResponseFileMode
+
+	// GlobalsAnywhere allows [Config.Globals] to be specified at any
+	// position in arguments, interleaved with commands and their options,
+	// as if they were all Persistent. E.g. 'prog build --verbose'.
+	//
+	// If a Command defines an Option with the same name as a global option,
+	// the Command's Option takes precedence, followed by Persistent options
+	// of parent commands, then Globals.
+	//
+	// Default: false
+	GlobalsAnywhere bool
+
+	// Abbreviate allows long option names and command names to be
+	// abbreviated to any prefix that is unique among names at the current
+	// level, e.g. '--verb' for '--verbose'. An exact match always takes
+	// precedence. An ambiguous prefix results in an error listing the
+	// candidates.
+	//
+	// Default: false
+	Abbreviate bool
+
+	// CollectErrors makes Parse continue after recoverable errors and
+	// return all of them joined using errors.Join instead of stopping at
+	// the first one.
+	//
+	// Recoverable errors are unknown options, invalid option values,
+	// missing required or indexed options and exclusivity violations. Any
+	// other error stops parsing and is returned joined with errors collected
+	// so far. No handler is invoked if any error occured.
+	//
+	// Default: false
+	CollectErrors bool
+
 	// ExecAllHandlers specifies that handlers of all commands in the execution
 	// chain parsed from Args will be executed in order as specified.
 	//
@@ -110,11 +179,21 @@ type Config struct {
 	// defaulted to DefaultShortPrefix by Parse() if left empty.
 	ShortPrefix string
 
+	// Terminator is the end of options token. It is optional and is
+	// defaulted to DefaultTerminator by Parse() if left empty.
+	//
+	// All arguments following the Terminator are not parsed as commands or
+	// options but are passed as text to [Indexed] and [Variadic] options of
+	// the current command, even if they start with an option prefix.
+	// E.g: 'exec -- -rf --foo' passes '-rf' and '--foo' to exec as text.
+	Terminator string
+
 	// PrintInDefinedOrder if true makes the print functions print options in
 	// the order they were defined.
 	//
 	// If disabled options are printed in groups by type:
-	//  Boolean, Optional, Required, Repeated, Indexed, Variadic
+	//  Boolean, Counted, Optional, OptionalValue, Required, Repeated,
+	//  KeyValue, Indexed, Variadic
 	// then by order of definition.
 	//
 	// Default: false.
@@ -131,6 +210,15 @@ type Config struct {
 	context context.Context
 	// chain is the chain of commands to execute determined by parse.
 	chain []*Command
+	// terminated is true if Terminator was parsed from Args.
+	terminated bool
+	// origins are response file locations of expanded Args, by index.
+	// Arguments not read from a response file have an empty origin.
+	origins []string
+	// argv are the arguments being parsed, after response file expansion.
+	argv Args
+	// errs are recoverable errors collected if CollectErrors is enabled.
+	errs []error
 }
 
 // Default returns a new default [Config] starting with args.
@@ -139,6 +227,7 @@ func Default(args ...string) *Config {
 		Args:        args,
 		LongPrefix:  DefaultLongPrefix,
 		ShortPrefix: DefaultShortPrefix,
+		Terminator:  DefaultTerminator,
 	}
 }
 
@@ -165,18 +254,20 @@ func ParseCtx(ctx context.Context, config *Config) error { return config.Parse(c
 // regardless if any arguments were given. In every case of no arguments it
 // returns [ErrNoArgs].
 //
-// Parse will first parse [Config.Globals] and call[Config.GlobalsHandler] if
-// set after they have been parsed. If no [Config.GlobalsHandler] is set,
-// [Config.Globals] may be inspected manually from the Config after parse.
-//
-// Parse then continues matching the following argument to a [Command],
-// parsing [Options] for that [Command]. If this is the last command in the
-// command execution chain  or [Config.ExecAllHandlers] is true, Command's
-// [Handler] is executed.
+// Parse will first parse [Config.Globals] then continue matching the
+// following argument to a [Command], parsing [Options] for that [Command].
 //
 // If the [Command] contains sub Commands and there are unparsed arguments left,
 // it continues parsing arguments into that Command's sub [Commands].
 //
+// Parse calls [Config.GlobalsHandler] if set after [Config.Globals] have
+// been parsed and validated, see [Config.GlobalsHandler] for details. If no
+// [Config.GlobalsHandler] is set, [Config.Globals] may be inspected manually
+// from the Config after parse. After all arguments have been parsed and
+// validated, for the last command in the command execution chain or for every
+// command in the chain if [Config.ExecAllHandlers] is true, Command's
+// [Handler] is executed.
+//
 // If an undefined Command or Option was specified, either due to a typo or
 // malformatted arguments Parse will return a descriptive error.
 //
@@ -238,11 +329,14 @@ func (self *Config) Parse(ctx context.Context) (err error) {
 
 	// Validation.
 	if self.Commands.Count() > 0 && optionsHaveVariadicOption(self.Globals) {
-		return errors.New("validation failed: globals contain a variadic option with command definitions present")
+		return validationErrorf(nil, "validation failed: globals contain a variadic option with command definitions present")
 	}
 	if err = ValidateOptions(self.Globals); err != nil {
 		return
 	}
+	if err = validateConstraints(self.GlobalConstraints, self.Globals); err != nil {
+		return
+	}
 	if err = ValidateCommands(self.Commands); err != nil {
 		return
 	}
@@ -254,33 +348,80 @@ func (self *Config) Parse(ctx context.Context) (err error) {
 	if self.ShortPrefix == "" {
 		self.ShortPrefix = DefaultShortPrefix
 	}
+	if self.Terminator == "" {
+		self.Terminator = DefaultTerminator
+	}
+	self.terminated = false
+	self.chain = nil
+	self.origins = nil
+	self.errs = nil
+
+	// Expand response files.
+	if self.ResponseFiles != NoResponseFiles {
+		if err = self.expandResponseFiles(); err != nil {
+			return
+		}
+	}
+	self.argv = self.Args
 
-	var w *wrapper
+	var (
+		w            *wrapper
+		globalsLater = self.GlobalsAnywhere || optionsHavePersistentOption(self.Globals)
+	)
 
-	// Process Globals
+	// Parse Globals then Commands. Globals are validated and handled before
+	// Commands are parsed unless they may be specified in arguments of
+	// Commands.
 	if err = self.Globals.parse(self); err != nil {
-		return
+		return self.collected(self.originError(err))
 	}
-	if err = validateExclusivityGroups(self.GlobalExclusivityGroups, self.Globals); err != nil {
-		return
+	if !globalsLater {
+		if err = self.validateGlobals(); err != nil {
+			return self.collected(err)
+		}
+		if !self.CollectErrors {
+			if err = self.handleGlobals(); err != nil {
+				return
+			}
+		}
 	}
-	w = &wrapper{
-		self.context,
-		self,
-		nil,
-		nil,
-		self.Globals,
+	if err = self.Commands.parse(self); err != nil {
+		return self.collected(self.originError(err))
 	}
-	if self.GlobalsHandler != nil {
-		if err = self.GlobalsHandler(w); err != nil {
-			return
+
+	// Apply fallback values and validate parsed options after all arguments
+	// were parsed as Persistent options may be specified in arguments of sub
+	// commands.
+	if globalsLater {
+		if err = self.validateGlobals(); err != nil {
+			return self.collected(err)
 		}
 	}
-
-	// Process Commands
-	if err = self.Commands.parse(self); err != nil {
+	for index, command := range self.chain {
+		var commands = self.chain[:index+1]
+		if err = command.Options.applyFallbacks(self, commands); err != nil {
+			return self.collected(err)
+		}
+		if err = command.Options.validateParsed(self, commands); err != nil {
+			return self.collected(err)
+		}
+		if err = validateCommandExclusivityGroups(self, commands); err != nil {
+			return self.collected(err)
+		}
+		if err = validateCommandConstraints(self, commands); err != nil {
+			return self.collected(err)
+		}
+	}
+	if err = self.collected(nil); err != nil {
 		return
 	}
+
+	// Invoke handlers.
+	if globalsLater || self.CollectErrors {
+		if err = self.handleGlobals(); err != nil {
+			return
+		}
+	}
 	if self.Commands.Count() == 0 || len(self.chain) == 0 {
 		return nil
 	}
@@ -307,6 +448,34 @@ func (self *Config) Parse(ctx context.Context) (err error) {
 	return nil
 }
 
+// validateGlobals applies fallback values to Globals and validates them.
+func (self *Config) validateGlobals() (err error) {
+	if err = self.Globals.applyFallbacks(self, nil); err != nil {
+		return
+	}
+	if err = self.Globals.validateParsed(self, nil); err != nil {
+		return
+	}
+	if err = validateExclusivityGroups(self, self.GlobalExclusivityGroups, self.Globals, nil); err != nil {
+		return
+	}
+	return checkConstraints(self, self.GlobalConstraints, self.Globals, nil)
+}
+
+// handleGlobals invokes GlobalsHandler, if set.
+func (self *Config) handleGlobals() error {
+	if self.GlobalsHandler == nil {
+		return nil
+	}
+	return self.GlobalsHandler(&wrapper{
+		self.context,
+		self,
+		nil,
+		nil,
+		self.Globals,
+	})
+}
+
 // Reset resets the state of all Commands and Options including Globals defined
 // in self, recursively. After calling Reset the Config is ready to be parsed.
 func (self *Config) Reset() {
@@ -378,8 +547,28 @@ type wrapper struct {
 	options Options
 }
 
+// find returns an Option by long name from wrapper options or from
+// Persistent options inherited by wrapper command. Returns nil if not found.
+func (self *wrapper) find(longName string) *Option {
+	if option := self.options.FindLong(longName); option != nil {
+		return option
+	}
+	if self.command == nil {
+		return nil
+	}
+	if index := slices.Index(self.config.chain, self.command); index >= 0 {
+		return persistentOptions(self.config, self.config.chain[:index]).FindLong(longName)
+	}
+	return nil
+}
+
 // Parsed implements [Context.Parsed].
-func (self *wrapper) Parsed(longName string) bool { return self.options.Parsed(longName) }
+func (self *wrapper) Parsed(longName string) bool {
+	if option := self.find(longName); option != nil {
+		return option.isSet()
+	}
+	return false
+}
 
 // Config implements [Context.Config].
 func (self *wrapper) Config() *Config { return self.config }
@@ -393,12 +582,13 @@ func (self *wrapper) ParentCommand() *Command { return self.parent }
 // Options implements [Context.Options].
 func (self *wrapper) Options() Options { return self.options }
 
+// Option implements [Context.Option].
+func (self *wrapper) Option(longName string) *Option { return self.find(longName) }
+
 // Values implements [Context.Values].
 func (self *wrapper) Values(longName string) Values {
-	for _, option := range self.options {
-		if option.LongName == longName {
-			return option.Values
-		}
+	if option := self.find(longName); option != nil {
+		return option.Values
 	}
 	return nil
 }
@@ -6,7 +6,12 @@ package cmdline
 
 import (
 	"errors"
+	"net"
+	"net/netip"
+	"net/url"
 	"os"
+	"regexp"
+	"slices"
 	"strings"
 	"testing"
 	"time"
@@ -525,6 +530,1145 @@ func TestCombinedBooleans(t *testing.T) {
 	}
 }
 
+func TestTerminator(t *testing.T) {
+	var (
+		config = Default("exec", "-f", "--", "-rf", "--foo", "bar")
+		force  bool
+		name   string
+		args   []string
+	)
+	config.Commands.Handle("exec", "Execute.", NopHandler).Options.
+		BooleanVar("force", "f", "Force.", &force).
+		IndexedVar("name", "Name.", &name).
+		VariadicVar("args", "Arguments.", &args)
+	if err := config.Parse(nil); err != nil {
+		t.Fatal(err)
+	}
+	if !force {
+		t.Fatal("force")
+	}
+	if name != "-rf" {
+		t.Fatal("indexed")
+	}
+	if strings.Join(args, " ") != "--foo bar" {
+		t.Fatal("variadic")
+	}
+
+	config = Default("exec", "--", "-f", "extra")
+	config.Commands.Handle("exec", "Execute.", NopHandler).Options.
+		Boolean("force", "f", "Force.").
+		Indexed("name", "Name.")
+	if err := config.Parse(nil); err == nil {
+		t.Fatal("expected unexpected argument error")
+	}
+
+	config, args = Default("exec", "x", "--", "-y", "--"), nil
+	config.Commands.Handle("exec", "Execute.", NopHandler).Options.
+		VariadicVar("args", "Arguments.", &args)
+	if err := config.Parse(nil); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(args, []string{"x", "-y", "--"}) {
+		t.Fatalf("variadic after terminator: %q", args)
+	}
+
+	for _, test := range []struct {
+		args    []string
+		options bool
+		fails   bool
+	}{
+		{[]string{"exec", "--"}, false, false},
+		{[]string{"exec", "--"}, true, false},
+		{[]string{"exec", "--", "x"}, false, true},
+		{[]string{"exec", "--", "x"}, true, true},
+	} {
+		config = Default(test.args...)
+		var exec = config.Commands.Handle("exec", "Execute.", NopHandler)
+		if test.options {
+			exec.Options.Boolean("force", "f", "Force.")
+		}
+		if err := config.Parse(nil); (err != nil) != test.fails {
+			t.Fatalf("%v: unexpected result: %v", test.args, err)
+		}
+	}
+}
+
+func TestNegatableBoolean(t *testing.T) {
+	var (
+		config = Default("--no-color", "--cache=false", "--force=true")
+		color  = true
+		cache  = true
+		force  = false
+	)
+	config.UseAssignment = true
+	config.Globals.Register(&Option{
+		LongName:  "color",
+		Kind:      Boolean,
+		Negatable: true,
+		Var:       &color,
+	})
+	config.Globals.
+		BooleanVar("cache", "c", "", &cache).
+		BooleanVar("force", "f", "", &force)
+	if err := config.Parse(nil); err != nil {
+		t.Fatal(err)
+	}
+	if color || cache || !force {
+		t.Fatal("negatable boolean failed")
+	}
+	if config.Globals.Parsed("color") || !config.Globals.FindLong("color").IsParsed || config.Globals.Values("color").First() != "false" {
+		t.Fatal("negated state")
+	}
+
+	config = Default("--no-cache")
+	config.Globals.Boolean("cache", "c", "")
+	if err := config.Parse(nil); err == nil {
+		t.Fatal("negated a non-negatable option")
+	}
+
+	var forced = true
+	config = Default("--no-color", "--mono", "--json=false", "--yaml", "--force=false")
+	config.UseAssignment = true
+	config.Globals.Register(&Option{LongName: "color", Kind: Boolean, Negatable: true})
+	config.Globals.Boolean("mono", "", "").Boolean("json", "", "").Boolean("yaml", "", "").Boolean("force", "", "")
+	config.GlobalExclusivityGroups = ExclusivityGroups{{"color", "mono"}}
+	config.GlobalConstraints = Constraints{ExactlyOne("json", "yaml")}
+	config.GlobalsHandler = func(c Context) error {
+		forced = c.Parsed("force")
+		return nil
+	}
+	if err := config.Parse(nil); err != nil {
+		t.Fatal(err)
+	}
+	if forced {
+		t.Fatal("false boolean reported as parsed")
+	}
+}
+
+func TestCountedOption(t *testing.T) {
+	for _, test := range []struct {
+		args  []string
+		count int
+	}{
+		{[]string{"-vvv"}, 3},
+		{[]string{"-v", "-v", "-v"}, 3},
+		{[]string{"--verbose", "-qv", "--verbose"}, 3},
+		{[]string{"--verbose=3"}, 3},
+	} {
+		var (
+			config  = Default(test.args...)
+			verbose int
+			count   int
+		)
+		config.UseAssignment = true
+		config.Globals.
+			CountedVar("verbose", "v", "Verbosity level.", &verbose).
+			Boolean("quiet", "q", "Be quiet.")
+		config.GlobalsHandler = func(c Context) error {
+			count = Get[int](c, "verbose")
+			return nil
+		}
+		if err := config.Parse(nil); err != nil {
+			t.Fatal(err)
+		}
+		if verbose != test.count || count != test.count {
+			t.Fatalf("%v: expected %d, got %d (var) %d (context)", test.args, test.count, verbose, count)
+		}
+	}
+}
+
+func TestAbbreviations(t *testing.T) {
+	var (
+		config  = Default("--verb", "inst", "--fo", "--no-col")
+		verbose bool
+		force   bool
+		color   = true
+	)
+	config.Abbreviate = true
+	config.Globals.
+		BooleanVar("verbose", "v", "", &verbose).
+		Boolean("version", "V", "")
+	var install = config.Commands.Handle("install", "", NopHandler)
+	install.Options.BooleanVar("force", "f", "", &force)
+	install.Options.Register(&Option{LongName: "color", Kind: Boolean, Negatable: true, Var: &color})
+	config.Commands.Handle("init", "", NopHandler)
+	if err := config.Parse(nil); err != nil {
+		t.Fatal(err)
+	}
+	if !verbose || !force || color || !install.executed {
+		t.Fatal("abbreviations failed")
+	}
+
+	config = Default("--co")
+	config.Abbreviate = true
+	config.Globals.
+		Counted("count", "c", "").
+		Boolean("color", "C", "")
+	if err := config.Parse(nil); err == nil || err.Error() != "--co is ambiguous: --count, --color" {
+		t.Fatalf("expected ambiguity error, got %v", err)
+	}
+
+	config = Default("in")
+	config.Abbreviate = true
+	config.Commands.Handle("install", "", NopHandler)
+	config.Commands.Handle("init", "", NopHandler)
+	if err := config.Parse(nil); err == nil || err.Error() != "in is ambiguous: install, init" {
+		t.Fatalf("expected ambiguity error, got %v", err)
+	}
+
+	config = Default("run", "--verb")
+	config.Abbreviate = true
+	config.Globals.Register(&Option{LongName: "verb", Kind: Boolean, Persistent: true})
+	config.Commands.Handle("run", "", NopHandler).Options.Boolean("verbose-log", "", "")
+	if err := config.Parse(nil); err != nil {
+		t.Fatal(err)
+	}
+	if !config.Globals.Parsed("verb") || config.Commands[0].Options.Parsed("verbose-log") {
+		t.Fatal("abbreviation took precedence over an exact inherited match")
+	}
+}
+
+func TestSuggestions(t *testing.T) {
+	var config = Default("instal")
+	config.Commands.Handle("install", "", NopHandler)
+	config.Commands.Handle("uninstall", "", NopHandler)
+	config.Commands.Handle("list", "", NopHandler)
+	var err = config.Parse(nil)
+	var ue *UnknownError
+	if !errors.As(err, &ue) || !ue.Command || len(ue.Suggestions) != 1 || ue.Suggestions[0] != "install" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err.Error() != "command 'instal' not registered, did you mean 'install'?" {
+		t.Fatalf("unexpected message: %v", err)
+	}
+
+	config = Default("--clor")
+	config.Globals.
+		Boolean("color", "c", "").
+		Boolean("colour", "C", "").
+		Boolean("verbose", "v", "")
+	if err = config.Parse(nil); err == nil || err.Error() != "unknown option 'clor', did you mean 'color' or 'colour'?" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, args := range [][]string{{"run", "-xv"}, {"run", "-v"}} {
+		config = Default(args...)
+		config.Globals.Register(&Option{LongName: "version", ShortName: "V", Kind: Boolean, Persistent: true})
+		config.Commands.Handle("run", "", NopHandler).Options.Boolean("all", "a", "")
+		if err = config.Parse(nil); !errors.As(err, &ue) || ue.Command || ue.Name != args[1][1:2] {
+			t.Fatalf("%v: unexpected error: %v", args, err)
+		}
+	}
+	if err.Error() != "unknown option 'v', did you mean 'V'?" {
+		t.Fatalf("unexpected message: %v", err)
+	}
+
+	for _, args := range [][]string{{"--verbos"}, {"run", "--verbos"}} {
+		config = Default(args...)
+		config.Globals.Register(&Option{LongName: "verbose", Kind: Boolean, Persistent: true})
+		config.Commands.Handle("run", "", NopHandler)
+		if err = config.Parse(nil); err == nil || err.Error() != "unknown option 'verbos', did you mean 'verbose'?" {
+			t.Fatalf("%v: unexpected error: %v", args, err)
+		}
+	}
+}
+
+func TestCommandAliases(t *testing.T) {
+	var (
+		config  = Default("rm", "item")
+		removed string
+	)
+	config.Commands.Register(HelpCommand(nil))
+	config.Commands.Handle("remove", "Remove an item.", func(c Context) error {
+		removed = c.Values("name").First()
+		return nil
+	}).SetAliases("rm", "del").Options.Indexed("name", "Item name.")
+	if err := config.Parse(nil); err != nil {
+		t.Fatal(err)
+	}
+	if removed != "item" {
+		t.Fatal("alias not resolved")
+	}
+
+	var buf = new(strings.Builder)
+	config = Default("help", "del")
+	config.Output = buf
+	config.Commands.Register(HelpCommand(nil))
+	config.Commands.Handle("remove", "Remove an item.", NopHandler).SetAliases("rm", "del")
+	if err := config.Parse(nil); err != ErrHelp {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "Aliases: rm, del") {
+		t.Fatalf("unexpected help output: %s", buf.String())
+	}
+
+	config = Default("rm")
+	config.Commands.Handle("remove", "", NopHandler).SetAliases("rm")
+	config.Commands.Handle("rm", "", NopHandler)
+	if err := config.Parse(nil); err == nil {
+		t.Fatal("expected alias conflict")
+	}
+}
+
+func TestOptionAliases(t *testing.T) {
+	var (
+		config = Default("--out", "file.txt", "-V")
+		output string
+		values Values
+	)
+	config.Globals.Register(&Option{
+		LongName:     "output",
+		ShortName:    "o",
+		LongAliases:  []string{"out"},
+		ShortAliases: []string{"O"},
+		Kind:         Optional,
+		Var:          &output,
+	})
+	config.Globals.Register(&Option{
+		LongName:     "verbose",
+		ShortName:    "v",
+		ShortAliases: []string{"V"},
+		Kind:         Boolean,
+	})
+	config.GlobalExclusivityGroups = ExclusivityGroups{{"output", "out"}}
+	config.GlobalsHandler = func(c Context) error {
+		values = c.Values("out")
+		return nil
+	}
+	if err := config.Parse(nil); err != nil {
+		t.Fatal(err)
+	}
+	if output != "file.txt" || values.First() != "file.txt" || !config.Globals.Parsed("verbose") {
+		t.Fatal("option aliases failed")
+	}
+
+	config = Default("-o", "x")
+	config.Globals.Optional("output", "o", "")
+	config.Globals.Register(&Option{LongName: "other", LongAliases: []string{"output"}, Kind: Optional})
+	if err := config.Parse(nil); err == nil {
+		t.Fatal("expected duplicate long name error")
+	}
+}
+
+func TestPersistentOptions(t *testing.T) {
+	var (
+		config  = Default("items", "add", "--verbose", "-d", "--force")
+		verbose bool
+		dryRun  bool
+		parsed  bool
+	)
+	config.Globals.Register(&Option{
+		LongName:   "verbose",
+		ShortName:  "v",
+		Kind:       Boolean,
+		Persistent: true,
+		Var:        &verbose,
+	})
+	var items = config.Commands.Handle("items", "Operate on items.", NopHandler)
+	items.Options.Register(&Option{
+		LongName:   "dry-run",
+		ShortName:  "d",
+		Kind:       Boolean,
+		Persistent: true,
+		Var:        &dryRun,
+	})
+	items.SubCommands.Handle("add", "Add an item.", func(c Context) error {
+		parsed = c.Parsed("verbose") && c.Parsed("dry-run") && c.Parsed("force")
+		return nil
+	}).Options.Boolean("force", "f", "Force it.")
+	if err := config.Parse(nil); err != nil {
+		t.Fatal(err)
+	}
+	if !verbose || !dryRun || !parsed {
+		t.Fatal("persistent options failed")
+	}
+
+	var buf = new(strings.Builder)
+	config = Default("help", "items", "add")
+	config.Output = buf
+	config.Commands.Register(HelpCommand(nil))
+	items = config.Commands.Handle("items", "Operate on items.", NopHandler)
+	items.Options.Register(&Option{LongName: "dry-run", Kind: Boolean, Persistent: true})
+	items.SubCommands.Handle("add", "Add an item.", NopHandler)
+	if err := config.Parse(nil); err != ErrHelp {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "Inherited options are:") || !strings.Contains(buf.String(), "--dry-run") {
+		t.Fatalf("unexpected help output: %s", buf.String())
+	}
+
+	config = Default("items", "add", "--force")
+	config.Commands.Handle("items", "", NopHandler).SubCommands.Handle("add", "", NopHandler)
+	config.Commands[0].Options.Boolean("force", "f", "")
+	if err := config.Parse(nil); err == nil {
+		t.Fatal("non persistent option inherited")
+	}
+
+	var errVersion = errors.New("version printed")
+	for _, persistent := range []bool{false, true} {
+		config = Default("--version", "bogus")
+		config.Globals.Register(&Option{LongName: "version", Kind: Boolean, Persistent: persistent})
+		config.Commands.Handle("run", "", NopHandler)
+		config.GlobalsHandler = func(c Context) error {
+			if c.Parsed("version") {
+				return errVersion
+			}
+			return nil
+		}
+		if err := config.Parse(nil); (err == errVersion) == persistent {
+			t.Fatalf("persistent %t: unexpected error: %v", persistent, err)
+		}
+	}
+}
+
+func TestGlobalsAnywhere(t *testing.T) {
+	var (
+		config       = Default("build", "--verbose", "release", "-o", "cmd", "--log", "x.log")
+		verbose      bool
+		globalOutput string
+		buildOutput  string
+		logFile      string
+	)
+	config.GlobalsAnywhere = true
+	config.Globals.
+		BooleanVar("verbose", "v", "", &verbose).
+		OptionalVar("output", "o", "", &globalOutput).
+		RequiredVar("log", "l", "", &logFile)
+	config.Commands.Handle("build", "", NopHandler).SubCommands.
+		Handle("release", "", NopHandler).Options.
+		OptionalVar("output", "o", "", &buildOutput)
+	if err := config.Parse(nil); err != nil {
+		t.Fatal(err)
+	}
+	if !verbose || globalOutput != "" || buildOutput != "cmd" || logFile != "x.log" {
+		t.Fatal("globals anywhere failed")
+	}
+
+	config = Default("build", "--verbose")
+	config.Globals.Boolean("verbose", "v", "")
+	config.Commands.Handle("build", "", NopHandler)
+	if err := config.Parse(nil); err == nil {
+		t.Fatal("global accepted after command")
+	}
+}
+
+func TestResponseFiles(t *testing.T) {
+	var (
+		dir   = t.TempDir()
+		write = func(name, data string) string {
+			var path = dir + "/" + name
+			if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+				t.Fatal(err)
+			}
+			return path
+		}
+		nested = write("nested.rsp", "-v\n# comment\n'a b' \"c \\\"d\\\"\"\n")
+		main   = write("main.rsp", "--name \"John Doe\"\n@"+nested+"\n")
+		name   string
+		files  []string
+	)
+	var config = Default("@"+main, "e")
+	config.ResponseFiles = ResponseFileTokens
+	config.Globals.
+		OptionalVar("name", "n", "", &name).
+		Boolean("verbose", "v", "").
+		VariadicVar("files", "", &files)
+	if err := config.Parse(nil); err != nil {
+		t.Fatal(err)
+	}
+	if name != "John Doe" || !config.Globals.Parsed("verbose") || strings.Join(files, "|") != `a b|c "d"|e` {
+		t.Fatalf("unexpected result: %s %v", name, files)
+	}
+
+	config = Default("@" + write("lines.rsp", "--name\nJane Doe\n\n"))
+	config.ResponseFiles = ResponseFileLines
+	config.Globals.OptionalVar("name", "n", "", &name)
+	if err := config.Parse(nil); err != nil || name != "Jane Doe" {
+		t.Fatalf("lines mode failed: %v %s", err, name)
+	}
+
+	var cycle = write("cycle.rsp", "@"+dir+"/cycle.rsp")
+	config = Default("@" + cycle)
+	config.ResponseFiles = ResponseFileTokens
+	if err := config.Parse(nil); err == nil || !strings.Contains(err.Error(), "includes itself") {
+		t.Fatalf("expected cycle error, got %v", err)
+	}
+
+	var bad = write("bad.rsp", "-v\n--bogus\n")
+	config = Default("@" + bad)
+	config.ResponseFiles = ResponseFileTokens
+	config.Globals.Boolean("verbose", "v", "")
+	if err := config.Parse(nil); err == nil || !strings.HasPrefix(err.Error(), bad+":2: ") {
+		t.Fatalf("expected error with origin, got %v", err)
+	}
+}
+
+func TestEnvironmentFallback(t *testing.T) {
+	t.Setenv("CMDLINE_TEST_TOKEN", "secret")
+	t.Setenv("CMDLINE_TEST_VERBOSE", "true")
+	t.Setenv("CMDLINE_TEST_NAME", "env")
+	var (
+		config  = Default("--name", "arg")
+		token   string
+		verbose bool
+		name    string
+		source  Source
+	)
+	config.Globals.Register(&Option{LongName: "token", Kind: Required, EnvVar: "CMDLINE_TEST_TOKEN", Var: &token})
+	config.Globals.Register(&Option{LongName: "verbose", Kind: Boolean, EnvVar: "CMDLINE_TEST_VERBOSE", Var: &verbose})
+	config.Globals.Register(&Option{LongName: "name", Kind: Optional, EnvVar: "CMDLINE_TEST_NAME", Var: &name})
+	config.GlobalsHandler = func(c Context) error {
+		source = c.Option("token").Source
+		return nil
+	}
+	if err := config.Parse(nil); err != nil {
+		t.Fatal(err)
+	}
+	if token != "secret" || !verbose || name != "arg" || source != SourceEnvironment {
+		t.Fatal("environment fallback failed")
+	}
+	if config.Globals.FindLong("name").Source != SourceArguments {
+		t.Fatal("argument source")
+	}
+
+	if help := getOptionHelp(config, "token"); !strings.Contains(help, "[env: CMDLINE_TEST_TOKEN]") {
+		t.Fatalf("unexpected help: %s", help)
+	}
+
+	for _, args := range [][]string{{"--password", "p"}, {}} {
+		config = Default(append(args, "--name", "x")...)
+		config.Globals.Register(&Option{LongName: "token", Kind: Optional, EnvVar: "CMDLINE_TEST_TOKEN"})
+		config.Globals.Register(&Option{LongName: "password", Kind: Optional})
+		config.Globals.Optional("name", "", "")
+		config.GlobalExclusivityGroups = ExclusivityGroups{{"token", "password"}}
+		config.GlobalConstraints = Constraints{ExactlyOne("token", "password")}
+		if err := config.Parse(nil); err != nil {
+			t.Fatalf("%v: %v", args, err)
+		}
+	}
+	config = Default("--password", "p", "--token", "t")
+	config.Globals.Register(&Option{LongName: "token", Kind: Optional, EnvVar: "CMDLINE_TEST_TOKEN"})
+	config.Globals.Register(&Option{LongName: "password", Kind: Optional})
+	config.GlobalExclusivityGroups = ExclusivityGroups{{"token", "password"}}
+	if err := config.Parse(nil); !errors.Is(err, ErrExclusive) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestDefaultValues(t *testing.T) {
+	var (
+		config  = Default("--retries", "5")
+		timeout time.Duration
+		retries int
+		values  Values
+		source  Source
+	)
+	config.Globals.Register(&Option{LongName: "timeout", Kind: Optional, Default: "30s", Var: &timeout})
+	config.Globals.Register(&Option{LongName: "retries", Kind: Optional, Default: "3", Var: &retries})
+	config.GlobalsHandler = func(c Context) error {
+		values, source = c.Values("timeout"), c.Option("timeout").Source
+		return nil
+	}
+	if err := config.Parse(nil); err != nil {
+		t.Fatal(err)
+	}
+	if timeout != 30*time.Second || retries != 5 {
+		t.Fatal("default values failed")
+	}
+	if values.First() != "30s" || source != SourceDefault || config.Globals.Parsed("timeout") {
+		t.Fatal("default value state")
+	}
+
+	if help := getOptionHelp(config, "timeout"); !strings.Contains(help, "(default: 30s)") {
+		t.Fatalf("unexpected help: %s", help)
+	}
+}
+
+func TestChoices(t *testing.T) {
+	var config *Config
+	for _, test := range []struct {
+		args    []string
+		message string
+	}{
+		{[]string{"--format=yaml", "a", "b"}, ""},
+		{[]string{"--format=xml"}, "invalid value 'xml' for option 'format', valid choices are: json, yaml, text"},
+		{[]string{"a", "c"}, "invalid value 'c' for option 'files', valid choices are: a, b"},
+	} {
+		config = Default(test.args...)
+		config.UseAssignment = true
+		config.Globals.Register(&Option{LongName: "format", Kind: Optional, Choices: []string{"json", "yaml", "text"}})
+		config.Globals.Register(&Option{LongName: "files", Kind: Variadic, Choices: []string{"a", "b"}})
+		if err := config.Parse(nil); (err != nil || test.message != "") && (err == nil || err.Error() != test.message) {
+			t.Fatalf("%v: unexpected error: %v", test.args, err)
+		}
+	}
+	if help := getOptionHelp(config, "format"); !strings.Contains(help, "(choices: json, yaml, text)") {
+		t.Fatalf("unexpected help: %s", help)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	var tests = []struct {
+		args     []string
+		kind     error
+		index    int
+		commands int
+		output   string
+	}{
+		{[]string{"--verbsoe"}, ErrUnknownOption, 0, 0, ""},
+		{[]string{"-v", "walk"}, ErrUnknownCommand, 1, 0, ""},
+		{[]string{"run", "--mode", "x", "--name"}, ErrMissingValue, 3, 1, ""},
+		{[]string{"run", "--verbose"}, ErrUnknownOption, 1, 1, ""},
+		{[]string{"run", "--name", "a"}, ErrMissingOption, -1, 1, ""},
+		{[]string{"-v", "-v", "run"}, ErrDuplicateOption, 1, 0, ""},
+		{[]string{"-v", "run", "--nmae", "a b"}, ErrUnknownOption, 2, 1,
+			"unknown option 'nmae', did you mean 'name'?\n  -v run --nmae \"a b\"\n         ^^^^^^\n"},
+	}
+	for _, test := range tests {
+		var config = Default(test.args...)
+		config.Globals.Boolean("verbose", "v", "Be verbose.")
+		config.Commands.Handle("run", "Run.", NopHandler).Options.
+			Optional("name", "n", "Name.").
+			Required("mode", "m", "Mode.")
+		var err = config.Parse(nil)
+		if !errors.Is(err, test.kind) {
+			t.Fatalf("%v: expected %v, got %v", test.args, test.kind, err)
+		}
+		var pe *ParseError
+		if !errors.As(err, &pe) {
+			t.Fatalf("%v: not a ParseError: %v", test.args, err)
+		}
+		if pe.Index != test.index || len(pe.Commands) != test.commands {
+			t.Fatalf("%v: unexpected index %d or commands %d", test.args, pe.Index, len(pe.Commands))
+		}
+		if pe.Index >= 0 && pe.Arg != test.args[pe.Index] {
+			t.Fatalf("%v: unexpected arg %s", test.args, pe.Arg)
+		}
+		var ue *UnknownError
+		if test.kind == ErrUnknownOption && (!errors.As(err, &ue) || "--"+ue.Name != pe.Arg) {
+			t.Fatalf("%v: not an UnknownError: %v", test.args, err)
+		}
+		if test.output != "" {
+			var buf = new(strings.Builder)
+			PrintError(buf, err)
+			if buf.String() != test.output {
+				t.Fatalf("%v: unexpected output: %q", test.args, buf.String())
+			}
+		}
+	}
+
+	var config = Default("-x")
+	config.Globals.Register(&Option{LongName: "", ShortName: "x", Kind: Boolean})
+	if err := config.Parse(nil); !errors.Is(err, ErrInvalidConfig) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestCollectErrors(t *testing.T) {
+	for _, test := range []struct {
+		args   []string
+		kinds  []error
+		carets int
+	}{
+		{
+			[]string{"--lvl", "--level=x", "run", "-x", "--fast", "--slow"},
+			[]error{ErrUnknownOption, ErrInvalidValue, ErrUnknownOption, ErrMissingOption, ErrMissingOption, ErrExclusive},
+			len("--lvl") + len("--level=x") + len("-x"),
+		},
+		{[]string{"-l", "run", "--bogus", "--mode=a"}, []error{ErrUnknownOption, ErrMissingOption}, len("--bogus")},
+		{[]string{"run", "--mode=a", "tgt"}, nil, 0},
+	} {
+		var (
+			config   = Default(test.args...)
+			executed bool
+			globals  bool
+		)
+		config.CollectErrors = true
+		config.UseAssignment = true
+		config.Globals.Counted("level", "l", "Level.")
+		config.GlobalsHandler = func(Context) error {
+			globals = true
+			return nil
+		}
+		config.Commands.Handle("run", "Run.", func(Context) error {
+			executed = true
+			return nil
+		}).Options.
+			Required("mode", "m", "Mode.").
+			Boolean("fast", "f", "Fast.").
+			Boolean("slow", "s", "Slow.").
+			Indexed("target", "Target.")
+		config.Commands.Find("run").ExclusivityGroups = ExclusivityGroups{{"fast", "slow"}}
+
+		var err = config.Parse(nil)
+		if test.kinds == nil {
+			if err != nil || !executed || !globals {
+				t.Fatalf("%v: unexpected result: %v", test.args, err)
+			}
+			continue
+		}
+		if err == nil || executed || globals {
+			t.Fatalf("%v: expected errors and no handler execution", test.args)
+		}
+		var errs = err.(interface{ Unwrap() []error }).Unwrap()
+		if len(errs) != len(test.kinds) {
+			t.Fatalf("%v: unexpected errors: %v", test.args, err)
+		}
+		for i, kind := range test.kinds {
+			if !errors.Is(errs[i], kind) {
+				t.Fatalf("%v: error %d: expected %v, got %v", test.args, i, kind, errs[i])
+			}
+		}
+		var buf = new(strings.Builder)
+		PrintError(buf, err)
+		if strings.Count(buf.String(), "^") != test.carets {
+			t.Fatalf("%v: unexpected output: %s", test.args, buf.String())
+		}
+	}
+}
+
+func TestKeyValue(t *testing.T) {
+	var (
+		defines map[string]string
+		limits  = map[string]int{"cpu": 1}
+	)
+	var config = Default("-D", "name=value", "--define", "other=a=b", "-L", "mem:512", "-D", "name=x")
+	config.Globals.KeyValueVar("define", "D", "Define a variable.", &defines)
+	config.Globals.Register(&Option{LongName: "limit", ShortName: "L", Kind: KeyValue, KeySeparator: ":", Var: &limits})
+	if err := config.Parse(nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(defines) != 2 || defines["name"] != "x" || defines["other"] != "a=b" {
+		t.Fatalf("unexpected defines: %v", defines)
+	}
+	if len(limits) != 2 || limits["cpu"] != 1 || limits["mem"] != 512 {
+		t.Fatalf("unexpected limits: %v", limits)
+	}
+
+	defines = nil
+	config = Default("-D", "name=value", "-D", "name=x")
+	config.Globals.Register(&Option{LongName: "define", ShortName: "D", Kind: KeyValue, DuplicateKeys: FirstKeyWins, Var: &defines})
+	if err := config.Parse(nil); err != nil || defines["name"] != "value" || config.Globals.Values("define").Count() != 1 {
+		t.Fatalf("unexpected result: %v, %v", err, defines)
+	}
+	config = Default("-D", "name=value", "-D", "name=x")
+	config.Globals.Register(&Option{LongName: "define", ShortName: "D", Kind: KeyValue, DuplicateKeys: RejectDuplicateKeys})
+	if err := config.Parse(nil); !errors.Is(err, ErrInvalidValue) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	config = Default("-D", "novalue")
+	config.Globals.KeyValue("define", "D", "Define a variable.")
+	if err := config.Parse(nil); err == nil || err.Error() != "option 'define' requires a value in <key>=<value> format" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if help := getOptionHelp(config, "define"); !strings.Contains(help, "--define <key>=<value>") {
+		t.Fatalf("unexpected help: %s", help)
+	}
+}
+
+func TestSeparator(t *testing.T) {
+	var tags, files []string
+	var config = Default("--tags=a,b", "--tags=\"c,d\",e", "x;y", "z")
+	config.UseAssignment = true
+	config.Globals.Register(&Option{LongName: "tags", Kind: Repeated, Separator: ",", Var: &tags})
+	config.Globals.Register(&Option{LongName: "files", Kind: Variadic, Separator: ";", Var: &files})
+	if err := config.Parse(nil); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(tags, []string{"a", "b", "c,d", "e"}) || !slices.Equal(config.Globals.Values("tags"), tags) {
+		t.Fatalf("unexpected tags: %v", tags)
+	}
+	if !slices.Equal(files, []string{"x", "y", "z"}) {
+		t.Fatalf("unexpected files: %v", files)
+	}
+
+	config = Default("--tags", "a,b")
+	config.Globals.Register(&Option{LongName: "tags", Kind: Optional, Separator: ","})
+	if err := config.Parse(nil); !errors.Is(err, ErrInvalidConfig) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestOptionalValue(t *testing.T) {
+	var tests = []struct {
+		assign bool
+		args   []string
+		color  string
+	}{
+		{true, []string{"--color", "run", "x"}, "auto"},
+		{true, []string{"--color=always", "run", "x"}, "always"},
+		{false, []string{"--color", "run", "x"}, "auto"},
+		{false, []string{"-c", "never", "run", "x"}, "never"},
+		{false, []string{"--color"}, "auto"},
+		{false, []string{"--color=always", "run", "x"}, "always"},
+		{false, []string{"--color=\"never\""}, "never"},
+	}
+	for _, test := range tests {
+		var (
+			config = Default(test.args...)
+			color  string
+		)
+		config.UseAssignment = test.assign
+		config.Globals.OptionalValueVar("color", "c", "Colorize output.", "auto", &color)
+		config.Commands.Handle("run", "Run.", NopHandler).Options.Indexed("target", "Target.")
+		if err := config.Parse(nil); err != nil {
+			t.Fatalf("%v: %v", test.args, err)
+		}
+		if color != test.color || !config.Globals.Parsed("color") {
+			t.Fatalf("%v: expected '%s', got '%s'", test.args, test.color, color)
+		}
+		if help := getOptionHelp(config, "color"); !strings.Contains(help, "--color[=<value>]") || !strings.Contains(help, "(implicit: auto)") {
+			t.Fatalf("unexpected help: %s", help)
+		}
+	}
+}
+
+func TestArity(t *testing.T) {
+	type Size struct{ Width, Height int }
+	var config *Config
+	for _, test := range []struct {
+		assign  bool
+		args    []string
+		points  []int
+		message string
+	}{
+		{false, []string{"--resize", "800", "600", "--range", "1", "10", "--point", "1", "2", "--point", "3", "4"}, []int{1, 2, 3, 4}, ""},
+		{true, []string{"--resize=800,600", "--range=1:10", "--point=1,2"}, []int{1, 2}, ""},
+		{false, []string{"--range", "1", "--resize", "1", "2"}, nil, "option 'range' expects 2 values, got 1"},
+		{true, []string{"--range=1:2:3"}, nil, "option 'range' expects 2 values, got 3"},
+	} {
+		var (
+			size   Size
+			rng    [2]float64
+			points []int
+		)
+		config = Default(test.args...)
+		config.UseAssignment = test.assign
+		config.Globals.Register(&Option{LongName: "resize", Kind: Optional, Arity: 2, Var: &size})
+		config.Globals.Register(&Option{LongName: "range", Kind: Required, Arity: 2, Separator: ":", Var: &rng})
+		config.Globals.Register(&Option{LongName: "point", Kind: Repeated, Arity: 2, Var: &points})
+		var err = config.Parse(nil)
+		if test.message != "" {
+			if !errors.Is(err, ErrMissingValue) || err.Error() != test.message {
+				t.Fatalf("%v: unexpected error: %v", test.args, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%v: %v", test.args, err)
+		}
+		if size != (Size{800, 600}) || rng != [2]float64{1, 10} || !slices.Equal(points, test.points) {
+			t.Fatalf("%v: unexpected values: %v %v %v", test.args, size, rng, points)
+		}
+	}
+	config.UseAssignment = false
+	if help := getOptionHelp(config, "resize"); !strings.Contains(help, "--resize <value> <value>") {
+		t.Fatalf("unexpected help: %s", help)
+	}
+}
+
+type upperValue string
+
+func (self *upperValue) String() string { return string(*self) }
+
+func (self *upperValue) Set(values Values) error {
+	*self = upperValue(strings.ToUpper(values.First()))
+	return nil
+}
+
+func TestTypedSlices(t *testing.T) {
+	var (
+		ints      []int
+		durations []time.Duration
+		times     []time.Time
+		ips       []net.IP
+		uppers    []upperValue
+		floats    []float64
+	)
+	var config = Default(
+		"--int", "1", "--int", "2",
+		"--duration", "1s", "--time", "2024-01-02T03:04:05Z",
+		"--ip", "127.0.0.1", "--upper", "a", "--upper", "b",
+		"1.5", "2.5",
+	)
+	config.Globals.RepeatedVar("int", "", "", &ints)
+	config.Globals.RepeatedVar("duration", "", "", &durations)
+	config.Globals.RepeatedVar("time", "", "", &times)
+	config.Globals.RepeatedVar("ip", "", "", &ips)
+	config.Globals.RepeatedVar("upper", "", "", &uppers)
+	config.Globals.VariadicVar("floats", "", &floats)
+	if err := config.Parse(nil); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(ints, []int{1, 2}) || !slices.Equal(durations, []time.Duration{time.Second}) ||
+		len(times) != 1 || times[0].Year() != 2024 || len(ips) != 1 || !ips[0].Equal(net.IPv4(127, 0, 0, 1)) ||
+		!slices.Equal(uppers, []upperValue{"A", "B"}) || !slices.Equal(floats, []float64{1.5, 2.5}) {
+		t.Fatalf("unexpected values: %v %v %v %v %v %v", ints, durations, times, ips, uppers, floats)
+	}
+
+	ints = nil
+	config = Default("--int", "1", "--int", "x", "--int", "3")
+	config.Globals.RepeatedVar("int", "", "", &ints)
+	var err = config.Parse(nil)
+	var ve *ValueError
+	var pe *ParseError
+	if !errors.As(err, &ve) || ve.Index != 1 || ve.Value != "x" || !errors.As(err, &pe) || pe.Index != 3 {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	floats = nil
+	config = Default("1", "x", "3")
+	config.Globals.VariadicVar("floats", "", &floats)
+	if err = config.Parse(nil); !errors.As(err, &ve) || ve.Index != 1 || !errors.As(err, &pe) || pe.Arg != "x" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestTypedOptions(t *testing.T) {
+	type Level int
+	var config = Default("--port", "8080", "--timeout", "5s", "run", "--level", "3", "-D", "a=1")
+	var port = Define[int](&config.Globals, Optional, "port", "p", "Port.")
+	var timeout = Register[time.Duration](&config.Globals, &Option{LongName: "timeout", Kind: Optional, Default: "1s"})
+	var retries = Define[uint](&config.Globals, Optional, "retries", "", "Retries.")
+	var (
+		level   Level
+		defines map[string]int
+		err     error
+	)
+	config.Commands.Handle("run", "Run.", func(c Context) error {
+		level = Get[Level](c, "level")
+		if defines, err = Lookup[map[string]int](c, "define"); err != nil {
+			return err
+		}
+		if _, err = Lookup[int](c, "missing"); err == nil {
+			return errors.New("expected an error for a missing option")
+		}
+		return nil
+	}).Options.Optional("level", "l", "Level.").KeyValue("define", "D", "Define.")
+	if err = config.Parse(nil); err != nil {
+		t.Fatal(err)
+	}
+	if port.Value() != 8080 || !port.Parsed() || timeout.Value() != 5*time.Second || retries.Value() != 0 || retries.Parsed() {
+		t.Fatalf("unexpected values: %v %v %v", port.Value(), timeout.Value(), retries.Value())
+	}
+	if level != 3 || defines["a"] != 1 {
+		t.Fatalf("unexpected values: %v %v", level, defines)
+	}
+
+	config = Default("--ch", "x")
+	Define[chan int](&config.Globals, Optional, "ch", "", "Channel.")
+	if err = config.Parse(nil); !errors.Is(err, ErrInvalidConfig) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestPointerVars(t *testing.T) {
+	var (
+		count    *int
+		name     *string
+		timeout  *time.Duration
+		ip       *net.IP
+		upper    *upperValue
+		ports    *[]int
+		omitted  *int
+		fallback *string
+	)
+	var config = Default("--count", "0", "--name", "", "--timeout", "2s", "--ip", "::1", "--upper", "x",
+		"--port", "1", "--port", "2")
+	config.Globals.OptionalVar("count", "", "", &count)
+	config.Globals.OptionalVar("name", "", "", &name)
+	config.Globals.OptionalVar("timeout", "", "", &timeout)
+	config.Globals.OptionalVar("ip", "", "", &ip)
+	config.Globals.OptionalVar("upper", "", "", &upper)
+	config.Globals.RepeatedVar("port", "", "", &ports)
+	config.Globals.OptionalVar("omitted", "", "", &omitted)
+	config.Globals.Register(&Option{LongName: "fallback", Kind: Optional, Default: "def", Var: &fallback})
+	if err := config.Parse(nil); err != nil {
+		t.Fatal(err)
+	}
+	if count == nil || *count != 0 || name == nil || *name != "" || timeout == nil || *timeout != 2*time.Second {
+		t.Fatalf("unexpected values: %v %v %v", count, name, timeout)
+	}
+	if ip == nil || !ip.Equal(net.IPv6loopback) || upper == nil || *upper != "X" || ports == nil || !slices.Equal(*ports, []int{1, 2}) {
+		t.Fatalf("unexpected values: %v %v %v", ip, upper, ports)
+	}
+	if omitted != nil || fallback == nil || *fallback != "def" {
+		t.Fatalf("unexpected values: %v %v", omitted, fallback)
+	}
+
+	config = Default("--count", "x")
+	count = nil
+	config.Globals.OptionalVar("count", "", "", &count)
+	if err := config.Parse(nil); err == nil || count != nil {
+		t.Fatalf("unexpected result: %v, %v", err, count)
+	}
+}
+
+func TestExtendedTypes(t *testing.T) {
+	var (
+		size    ByteSize
+		sizes   []ByteSize
+		ip      net.IP
+		network net.IPNet
+		prefix  netip.Prefix
+		link    *url.URL
+		pattern *regexp.Regexp
+		mode    os.FileMode
+		hex     int
+		bin     uint8
+		dec     int
+		date    time.Time
+	)
+	var config = Default(
+		"--size", "10MiB", "--sizes", "1.5G", "--sizes", "4kb", "--sizes", "512",
+		"--ip", "10.0.0.1", "--network", "10.0.0.0/8", "--prefix", "fd00::/8",
+		"--link", "https://example.com/path", "--pattern", "^a+$", "--mode", "0755",
+		"--hex", "0x1F", "--bin", "0b101", "--dec", "010", "--date", "2024-03-15",
+	)
+	config.Globals.OptionalVar("size", "", "", &size)
+	config.Globals.RepeatedVar("sizes", "", "", &sizes)
+	config.Globals.OptionalVar("ip", "", "", &ip)
+	config.Globals.OptionalVar("network", "", "", &network)
+	config.Globals.OptionalVar("prefix", "", "", &prefix)
+	config.Globals.OptionalVar("link", "", "", &link)
+	config.Globals.OptionalVar("pattern", "", "", &pattern)
+	config.Globals.OptionalVar("mode", "", "", &mode)
+	config.Globals.OptionalVar("hex", "", "", &hex)
+	config.Globals.OptionalVar("bin", "", "", &bin)
+	config.Globals.OptionalVar("dec", "", "", &dec)
+	config.Globals.Register(&Option{LongName: "date", Kind: Optional, Layout: "2006-01-02", Var: &date})
+	if err := config.Parse(nil); err != nil {
+		t.Fatal(err)
+	}
+	if size != 10<<20 || size.String() != "10MiB" || !slices.Equal(sizes, []ByteSize{1500000000, 4000, 512}) {
+		t.Fatalf("unexpected sizes: %v %v", size, sizes)
+	}
+	if !ip.Equal(net.IPv4(10, 0, 0, 1)) || network.String() != "10.0.0.0/8" || prefix.String() != "fd00::/8" {
+		t.Fatalf("unexpected addresses: %v %v %v", ip, network, prefix)
+	}
+	if link == nil || link.Host != "example.com" || pattern == nil || !pattern.MatchString("aaa") || mode != 0755 {
+		t.Fatalf("unexpected values: %v %v %v", link, pattern, mode)
+	}
+	if hex != 31 || bin != 5 || dec != 10 || date.Day() != 15 {
+		t.Fatalf("unexpected values: %v %v %v %v", hex, bin, dec, date)
+	}
+
+	for _, test := range []struct {
+		option, value, message string
+	}{
+		{"size", "10XB", "invalid byte size '10XB'"},
+		{"mode", "0799", "invalid file mode '0799', expected an octal number"},
+	} {
+		config = Default("--"+test.option, test.value)
+		config.Globals.OptionalVar("size", "", "", &size)
+		config.Globals.OptionalVar("mode", "", "", &mode)
+		if err := config.Parse(nil); err == nil || !strings.HasSuffix(err.Error(), test.message) {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+}
+
+func TestValidators(t *testing.T) {
+	var dir = t.TempDir()
+	var file = dir + "/file.txt"
+	if err := os.WriteFile(file, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	var config *Config
+	for _, test := range []struct {
+		args    []string
+		message string
+	}{
+		{[]string{"--port=0x50", "--name=abc", "--input=" + file, "--dir=" + dir, "--output=" + dir + "/new.txt", "--url=HTTPS://example.com"}, ""},
+		{[]string{"--port=70000"}, "invalid value '70000' for option 'port': must be between 1 and 65535"},
+		{[]string{"--port=x"}, "invalid value 'x' for option 'port': must be a number"},
+		{[]string{"--port=NaN"}, "invalid value 'NaN' for option 'port': must be a finite number"},
+		{[]string{"--port=-Inf"}, "invalid value '-Inf' for option 'port': must be a finite number"},
+		{[]string{"--name=a"}, "invalid value 'a' for option 'name': must be 2 to 8 characters long"},
+		{[]string{"--name=ABC"}, "invalid value 'ABC' for option 'name': must be matching ^[a-z]+$"},
+		{[]string{"--input=" + dir + "/none"}, "invalid value '" + dir + "/none' for option 'input': file does not exist"},
+		{[]string{"--dir=" + file}, "invalid value '" + file + "' for option 'dir': must be a directory"},
+		{[]string{"--output=" + dir + "/none/x"}, "invalid value '" + dir + "/none/x' for option 'output': directory '" + dir + "/none' is not writable"},
+		{[]string{"--url=ftp://example.com"}, "invalid value 'ftp://example.com' for option 'url': must be a URL with scheme http or https"},
+	} {
+		config = Default(test.args...)
+		config.UseAssignment = true
+		config.Globals.Register(&Option{LongName: "port", Kind: Optional, Validators: []Validator{Range(1, 65535)}})
+		config.Globals.Register(&Option{LongName: "name", Kind: Optional, Validators: []Validator{Length(2, 8), Matches("^[a-z]+$")}})
+		config.Globals.Register(&Option{LongName: "input", Kind: Repeated, Validators: []Validator{ExistingFile()}})
+		config.Globals.Register(&Option{LongName: "dir", Kind: Optional, Validators: []Validator{ExistingDir()}})
+		config.Globals.Register(&Option{LongName: "output", Kind: Optional, Validators: []Validator{WritablePath()}})
+		config.Globals.Register(&Option{LongName: "url", Kind: Optional, Validators: []Validator{URLScheme("http", "https")}})
+		var err = config.Parse(nil)
+		if test.message == "" {
+			if err != nil {
+				t.Fatalf("%v: %v", test.args, err)
+			}
+		} else if !errors.Is(err, ErrInvalidValue) || err.Error() != test.message {
+			t.Fatalf("%v: unexpected error: %v", test.args, err)
+		}
+	}
+	if help := getOptionHelp(config, "name"); !strings.Contains(help, "(2 to 8 characters) (matching ^[a-z]+$)") {
+		t.Fatalf("unexpected help: %s", help)
+	}
+}
+
+func TestConstraints(t *testing.T) {
+	var config *Config
+	for _, test := range []struct {
+		args    []string
+		collect bool
+		kind    error
+		message string
+	}{
+		{[]string{"--key=a", "--cert=b", "--user=u", "--password=p", "export", "--format=file", "--out=x", "--json"}, false, nil, ""},
+		{[]string{"export", "--yaml"}, false, nil, ""},
+		{[]string{"--key=a", "export", "--json"}, false, ErrMissingOption, "option 'key' requires option 'cert'"},
+		{[]string{"--user=u", "export", "--json"}, false, ErrConstraint, "options 'user', 'password' must be given together"},
+		{[]string{"export", "--format=file", "--json"}, false, ErrMissingOption, "command 'export' option 'out' is required if option 'format' is 'file'"},
+		{[]string{"export"}, false, ErrConstraint, "command 'export' exactly one of options 'json', 'yaml' is required"},
+		{[]string{"export", "--json", "--yaml"}, false, ErrConstraint, "command 'export' exactly one of options 'json', 'yaml' is required"},
+		{[]string{"--key=a", "export"}, true, ErrConstraint, "option 'key' requires option 'cert'\ncommand 'export' exactly one of options 'json', 'yaml' is required"},
+	} {
+		config = Default(test.args...)
+		config.UseAssignment = true
+		config.CollectErrors = test.collect
+		config.Globals.Register(&Option{LongName: "key", Kind: Optional})
+		config.Globals.Register(&Option{LongName: "cert", Kind: Optional})
+		config.Globals.Register(&Option{LongName: "user", Kind: Optional})
+		config.Globals.Register(&Option{LongName: "password", Kind: Optional})
+		config.GlobalConstraints = Constraints{Requires("key", "cert"), AllOrNone("user", "password")}
+		var command = config.Commands.Handle("export", "Export data.", NopHandler)
+		command.Options.Register(&Option{LongName: "format", Kind: Optional, Default: "text"})
+		command.Options.Register(&Option{LongName: "out", Kind: Optional})
+		command.Options.Register(&Option{LongName: "json", Kind: Boolean})
+		command.Options.Register(&Option{LongName: "yaml", Kind: Boolean})
+		command.Constraints = Constraints{RequiredIf("format", "file", "out"), ExactlyOne("json", "yaml")}
+		var err = config.Parse(nil)
+		if test.kind == nil {
+			if err != nil {
+				t.Fatalf("%v: %v", test.args, err)
+			}
+		} else if !errors.Is(err, test.kind) || err.Error() != test.message {
+			t.Fatalf("%v: unexpected error: %v", test.args, err)
+		}
+	}
+
+	var buf = new(strings.Builder)
+	PrintConstraints(buf, config, config.Commands.Find("export").Constraints, 0)
+	if buf.String() != "--out required if --format is 'file'\nexactly one of --json, --yaml\n" {
+		t.Fatalf("unexpected help: %s", buf.String())
+	}
+
+	config = Default("--json")
+	config.Globals.Boolean("json", "", "")
+	config.GlobalConstraints = Constraints{AtLeastOne("json", "xml")}
+	if err := config.Parse(nil); !errors.Is(err, ErrInvalidConfig) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
 func getPrettyPrintDemoConfig() (out *Config) {
 
 	out = Default()
@@ -574,6 +1718,14 @@ func getPrettyPrintDemoConfig() (out *Config) {
 	return
 }
 
+// getOptionHelp returns the help line of the global option longName in
+// config as printed by PrintOption.
+func getOptionHelp(config *Config, longName string) string {
+	var buf = new(strings.Builder)
+	PrintOption(buf, config, config.Globals.FindLong(longName), 0)
+	return buf.String()
+}
+
 type BindData struct {
 	Name   string
 	Age    int
@@ -6,6 +6,8 @@ package cmdline
 
 import (
 	"context"
+	"slices"
+	"strings"
 )
 
 // Handler is a Command invocation callback prototype. It carries a Command
@@ -36,14 +38,29 @@ type Context interface {
 	context.Context
 
 	// Parsed returns true if an [Option] with specified LongName was parsed.
+	// It returns false for a [Boolean] Option given a false value, e.g.
+	// '--no-color' or '--color=false'.
+	//
+	// Parsed, Values and Option address the Command's Options first then the
+	// Persistent Options inherited from parent commands and Globals.
 	Parsed(string) bool
 
 	// Values returns an array of strings that were passed to the
 	// [Option] under specified LongName or Name.
 	//
-	// Unparsed Options and options that take no arguments return nil.
+	// Unparsed Options and options that take no arguments return nil unless
+	// the Option has a [Option.Default], which is returned instead. See
+	// [Option.Source] to distinguish a default value from a parsed one.
+	// Boolean options return "false" if negated or the assigned value if
+	// assigned explicitly.
 	Values(string) Values
 
+	// Option returns the [Option] under specified LongName or nil if not
+	// found. It gives access to the complete Option state such as
+	// [Option.Source]. See [Get] and [Lookup] for typed access to Option
+	// values.
+	Option(string) *Option
+
 	// Config returns the config that is being used to parse.
 	Config() *Config
 
@@ -70,6 +87,13 @@ type Command struct {
 	// Commands.
 	Name string
 
+	// Aliases are optional alternative names by which the Command may be
+	// invoked from arguments, e.g. 'rm' and 'del' for 'remove'.
+	//
+	// Aliases must not be empty and must be unique among names and aliases
+	// of all Commands in Commands.
+	Aliases []string
+
 	// Help is the short Command help text displayed in command listing that 
 	// should prefferably fit the standard width of a terminal.
 	Help string
@@ -106,6 +130,13 @@ type Command struct {
 	// If no ExclusivityGroups are defined no checking is performed.
 	ExclusivityGroups ExclusivityGroups
 
+	// Constraints are the dependency constraints for this Command's Options.
+	// If parsed Options violate a Constraint Parse/ParseCtx will return an
+	// error. Constraints are summarized in help.
+	//
+	// If no Constraints are defined no checking is performed.
+	Constraints Constraints
+
 	// executed is true if the command was parsed from arguments.
 	executed bool
 }
@@ -116,6 +147,22 @@ func (self *Command) SetDoc(doc string) *Command {
 	return self
 }
 
+// SetAliases sets [Command.Aliases] and returns self.
+func (self *Command) SetAliases(aliases ...string) *Command {
+	self.Aliases = aliases
+	return self
+}
+
+// Names returns the Command name followed by its aliases.
+func (self *Command) Names() []string {
+	return append([]string{self.Name}, self.Aliases...)
+}
+
+// HasName returns true if name equals Command name or one of its aliases.
+func (self *Command) HasName(name string) bool {
+	return slices.Contains(self.Names(), name)
+}
+
 // ExclusivityGroup defines a group of option names which are mutually
 // exclusive and may not be passed together to a command at the same time.
 type ExclusivityGroup []string
@@ -150,16 +197,34 @@ func (self *Commands) Handle(name, help string, h Handler) (c *Command) {
 	return
 }
 
-// Find returns a Command from self by name or nil if not found.
+// Find returns a Command from self by name or alias or nil if not found.
 func (self Commands) Find(name string) *Command {
 	for i := 0; i < len(self); i++ {
-		if self[i].Name == name {
+		if self[i].HasName(name) {
 			return self[i]
 		}
 	}
 	return nil
 }
 
+// Match returns Commands from self whose names or aliases start with prefix.
+// If a Command with name or alias equal to prefix exists only that Command is
+// returned.
+func (self Commands) Match(prefix string) (out Commands) {
+	if command := self.Find(prefix); command != nil {
+		return Commands{command}
+	}
+	for _, command := range self {
+		for _, name := range command.Names() {
+			if strings.HasPrefix(name, prefix) {
+				out = append(out, command)
+				break
+			}
+		}
+	}
+	return
+}
+
 // AnyExecuted returns true if any commands in Commands was executed.
 func (self Commands) AnyExecuted() bool {
 	for _, command := range self {
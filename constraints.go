@@ -0,0 +1,230 @@
+// Copyright 2023-2024 Vedran Vuk. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package cmdline
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// ConstraintKind defines the kind of a [Constraint].
+type ConstraintKind int
+
+const (
+	// ConstraintInvalid is the invalid, zero value of a ConstraintKind.
+	ConstraintInvalid ConstraintKind = iota
+	// ConstraintRequires requires that all of [Constraint.Options] are given
+	// if [Constraint.Option] is given.
+	ConstraintRequires
+	// ConstraintRequiredIf requires that all of [Constraint.Options] are
+	// given if [Constraint.Option] has [Constraint.Value].
+	ConstraintRequiredIf
+	// ConstraintAtLeastOne requires that at least one of [Constraint.Options]
+	// is given.
+	ConstraintAtLeastOne
+	// ConstraintExactlyOne requires that exactly one of [Constraint.Options]
+	// is given.
+	ConstraintExactlyOne
+	// ConstraintAllOrNone requires that either all or none of
+	// [Constraint.Options] are given.
+	ConstraintAllOrNone
+)
+
+// Constraint defines a dependency between options of an [Options] set, such
+// as an option that requires another option.
+//
+// Constraints are checked after all arguments were parsed and fallback values
+// applied, alongside [ExclusivityGroups]. An option satisfies a constraint if
+// it was parsed from arguments or an environment variable; default values do
+// not count as given. For ConstraintExactlyOne options read from environment
+// are ignored if any of the options was given in arguments. Names may be long
+// names or long aliases.
+//
+// Constraints are best constructed using [Requires], [RequiredIf],
+// [AtLeastOne], [ExactlyOne] and [AllOrNone].
+type Constraint struct {
+	// Kind is the kind of the constraint.
+	Kind ConstraintKind
+	// Option is the long name of the option the constraint depends on.
+	// It is used by ConstraintRequires and ConstraintRequiredIf only.
+	Option string
+	// Value is the value Option must have for ConstraintRequiredIf to
+	// require Options. Default values of Option are considered as well.
+	Value string
+	// Options are the long names of options the constraint applies to.
+	Options []string
+}
+
+// Constraints is a set of [Constraint].
+type Constraints []Constraint
+
+// Requires returns a [Constraint] that requires options if option is given.
+func Requires(option string, options ...string) Constraint {
+	return Constraint{Kind: ConstraintRequires, Option: option, Options: options}
+}
+
+// RequiredIf returns a [Constraint] that requires options if option has value.
+func RequiredIf(option, value string, options ...string) Constraint {
+	return Constraint{Kind: ConstraintRequiredIf, Option: option, Value: value, Options: options}
+}
+
+// AtLeastOne returns a [Constraint] that requires at least one of options.
+func AtLeastOne(options ...string) Constraint {
+	return Constraint{Kind: ConstraintAtLeastOne, Options: options}
+}
+
+// ExactlyOne returns a [Constraint] that requires exactly one of options.
+func ExactlyOne(options ...string) Constraint {
+	return Constraint{Kind: ConstraintExactlyOne, Options: options}
+}
+
+// AllOrNone returns a [Constraint] that requires all or none of options.
+func AllOrNone(options ...string) Constraint {
+	return Constraint{Kind: ConstraintAllOrNone, Options: options}
+}
+
+// String returns the constraint description as printed in help.
+func (self Constraint) String() string { return self.describe(DefaultLongPrefix) }
+
+// describe returns the constraint description with option names prefixed
+// with prefix.
+func (self Constraint) describe(prefix string) string {
+	var names = make([]string, 0, len(self.Options))
+	for _, name := range self.Options {
+		names = append(names, prefix+name)
+	}
+	var list = strings.Join(names, ", ")
+	switch self.Kind {
+	case ConstraintRequires:
+		return fmt.Sprintf("%s%s requires %s", prefix, self.Option, list)
+	case ConstraintRequiredIf:
+		return fmt.Sprintf("%s required if %s%s is '%s'", list, prefix, self.Option, self.Value)
+	case ConstraintAtLeastOne:
+		return fmt.Sprintf("at least one of %s", list)
+	case ConstraintExactlyOne:
+		return fmt.Sprintf("exactly one of %s", list)
+	case ConstraintAllOrNone:
+		return fmt.Sprintf("all or none of %s", list)
+	default:
+		return "invalid constraint"
+	}
+}
+
+// validateConstraints returns nil if constraints are properly defined and
+// refer only to options defined in options or an error otherwise.
+func validateConstraints(constraints Constraints, options Options) error {
+	for _, constraint := range constraints {
+		var names = constraint.Options
+		switch constraint.Kind {
+		case ConstraintRequires, ConstraintRequiredIf:
+			if constraint.Option == "" || len(constraint.Options) == 0 {
+				return validationErrorf(nil, "validation failed: constraint '%s' requires an option and options it depends on", constraint)
+			}
+			names = append([]string{constraint.Option}, names...)
+		case ConstraintAtLeastOne, ConstraintExactlyOne, ConstraintAllOrNone:
+			if len(constraint.Options) < 2 {
+				return validationErrorf(nil, "validation failed: constraint '%s' requires at least two options", constraint)
+			}
+		default:
+			return validationErrorf(nil, "validation failed: invalid constraint kind")
+		}
+		for _, name := range names {
+			if options.FindLong(name) == nil {
+				return validationErrorf(nil, "validation failed: constraint '%s' refers to undefined option '%s'", constraint, name)
+			}
+		}
+	}
+	return nil
+}
+
+// validateCommandConstraints calls checkConstraints for the last command in
+// the chain of commands.
+func validateCommandConstraints(config *Config, commands []*Command) error {
+	var command = commands[len(commands)-1]
+	return checkConstraints(config, command.Constraints, command.Options, commands)
+}
+
+// checkConstraints returns nil if parsed options satisfy all constraints or
+// an error otherwise. Options are defined in the chain of commands.
+// Violations are collected by config if it collects errors.
+func checkConstraints(config *Config, constraints Constraints, options Options, commands []*Command) (err error) {
+	for _, constraint := range constraints {
+		var (
+			given, missing = givenOptions(options, constraint.Options)
+			option         = options.FindLong(constraint.Option)
+			kind           = ErrConstraint
+		)
+		switch constraint.Kind {
+		case ConstraintRequires:
+			if option == nil || !option.isSet() || len(missing) == 0 {
+				continue
+			}
+			err = fmt.Errorf("option '%s' requires option '%s'", constraint.Option, missing[0])
+			option, kind = options.FindLong(missing[0]), ErrMissingOption
+		case ConstraintRequiredIf:
+			if option == nil || !slices.Contains(option.Values, constraint.Value) || len(missing) == 0 {
+				continue
+			}
+			err = fmt.Errorf("option '%s' is required if option '%s' is '%s'", missing[0], constraint.Option, constraint.Value)
+			option, kind = options.FindLong(missing[0]), ErrMissingOption
+		case ConstraintAtLeastOne:
+			if len(given) > 0 {
+				continue
+			}
+			err = fmt.Errorf("at least one of options %s is required", quoteNames(constraint.Options))
+		case ConstraintExactlyOne:
+			if givenInArguments(options, constraint.Options) {
+				given = slices.DeleteFunc(given, func(option *Option) bool {
+					return !isGiven(option, true)
+				})
+			}
+			if len(given) == 1 {
+				continue
+			}
+			err = fmt.Errorf("exactly one of options %s is required", quoteNames(constraint.Options))
+			if len(given) > 1 {
+				option = given[1]
+			}
+		case ConstraintAllOrNone:
+			if len(given) == 0 || len(missing) == 0 {
+				continue
+			}
+			err = fmt.Errorf("options %s must be given together", quoteNames(constraint.Options))
+			option = options.FindLong(missing[0])
+		default:
+			continue
+		}
+		if len(commands) > 0 {
+			err = fmt.Errorf("command '%s' %w", commands[len(commands)-1].Name, err)
+		}
+		if err = config.collect(optionError(kind, option, commands, err)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// givenOptions returns distinct options named by names that were given and
+// names of options that were not given.
+func givenOptions(options Options, names []string) (given Options, missing []string) {
+	for _, name := range names {
+		var option = options.FindLong(name)
+		switch {
+		case option != nil && option.isSet():
+			if !slices.Contains(given, option) {
+				given = append(given, option)
+			}
+		case option == nil || !slices.ContainsFunc(missing, option.HasLongName):
+			missing = append(missing, name)
+		}
+	}
+	return
+}
+
+// quoteNames returns names quoted and separated by a comma.
+func quoteNames(names []string) string {
+	return "'" + strings.Join(names, "', '") + "'"
+}
@@ -0,0 +1,210 @@
+// Copyright 2023-2024 Vedran Vuk. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package cmdline
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Error kinds of a [ParseError]. They can be tested against an error returned
+// by Parse using errors.Is.
+var (
+	// ErrInvalidConfig is the kind of error returned if [Config] definition
+	// failed validation.
+	ErrInvalidConfig = errors.New("invalid config")
+	// ErrUnknownCommand is the kind of error returned if an argument refers
+	// to a [Command] that is not registered.
+	ErrUnknownCommand = errors.New("unknown command")
+	// ErrUnknownOption is the kind of error returned if an argument refers
+	// to an [Option] that is not registered.
+	ErrUnknownOption = errors.New("unknown option")
+	// ErrAmbiguous is the kind of error returned if an abbreviated argument
+	// matches more than one [Command] or [Option].
+	ErrAmbiguous = errors.New("ambiguous argument")
+	// ErrUnexpectedArgument is the kind of error returned if an argument is
+	// not valid at its position.
+	ErrUnexpectedArgument = errors.New("unexpected argument")
+	// ErrMissingValue is the kind of error returned if an [Option] that
+	// requires a value was given none.
+	ErrMissingValue = errors.New("missing value")
+	// ErrInvalidValue is the kind of error returned if an [Option] was given
+	// a value it does not accept or that could not be converted to its
+	// variable.
+	ErrInvalidValue = errors.New("invalid value")
+	// ErrDuplicateOption is the kind of error returned if an [Option] that
+	// may be given only once was given multiple times.
+	ErrDuplicateOption = errors.New("duplicate option")
+	// ErrMissingOption is the kind of error returned if a [Required] or an
+	// [Indexed] [Option] was not given.
+	ErrMissingOption = errors.New("missing option")
+	// ErrMissingCommand is the kind of error returned if a [Command] that
+	// requires execution of one of its sub commands was given none.
+	ErrMissingCommand = errors.New("missing command")
+	// ErrExclusive is the kind of error returned if mutually exclusive
+	// options were given together.
+	ErrExclusive = errors.New("mutually exclusive options")
+	// ErrConstraint is the kind of error returned if given options violate
+	// a [Constraint] other than a missing required option.
+	ErrConstraint = errors.New("constraint violated")
+)
+
+// ParseError is the error returned by Parse if parsing or validation failed.
+//
+// It describes the kind of the error and, where applicable, the argument,
+// [Option] and chain of [Command] that caused it.
+type ParseError struct {
+	// Kind is the kind of the error, one of the Err* error kinds.
+	Kind error
+	// Arg is the offending argument or empty if the error does not refer to
+	// an argument.
+	Arg string
+	// Index is the index of Arg in [Config.Args] as given to Parse, after
+	// response file expansion, or -1 if the error does not refer to an
+	// argument.
+	Index int
+	// Option is the offending Option, if any.
+	Option *Option
+	// Commands is the chain of Commands being parsed or validated when the
+	// error occured, outermost first. It is empty for Globals.
+	Commands []*Command
+	// Err is the underlying error that describes the error.
+	Err error
+
+	// args are the arguments being parsed when the error occured.
+	args Args
+}
+
+// Error implements the error interface.
+func (self *ParseError) Error() string { return self.Err.Error() }
+
+// Unwrap returns error Kind and the underlying error.
+func (self *ParseError) Unwrap() []error { return []error{self.Kind, self.Err} }
+
+// Caret returns the arguments being parsed when the error occured on the
+// first line and a caret under the offending argument on the second line.
+// Arguments containing spaces are quoted.
+//
+// It returns an empty string if the error does not refer to an argument.
+func (self *ParseError) Caret() string {
+	if self.Index < 0 || self.Index >= len(self.args) {
+		return ""
+	}
+	var line, caret strings.Builder
+	for index, arg := range self.args {
+		if index > 0 {
+			line.WriteByte(' ')
+			caret.WriteByte(' ')
+		}
+		if arg == "" || strings.ContainsFunc(arg, unicode.IsSpace) {
+			arg = strconv.Quote(arg)
+		}
+		line.WriteString(arg)
+		var mark = " "
+		if index == self.Index {
+			mark = "^"
+		}
+		caret.WriteString(strings.Repeat(mark, utf8.RuneCountInString(arg)))
+	}
+	return line.String() + "\n" + strings.TrimRight(caret.String(), " ")
+}
+
+// ValueError is a conversion error of a single value of an [Option] that
+// takes multiple values, e.g. a [Repeated] option mapped to a slice.
+type ValueError struct {
+	// Index is the index of the value in Option's Values.
+	Index int
+	// Value is the value that failed to convert.
+	Value string
+	// Err is the conversion error.
+	Err error
+}
+
+// Error implements the error interface.
+func (self *ValueError) Error() string {
+	return fmt.Sprintf("value #%d '%s': %v", self.Index+1, self.Value, self.Err)
+}
+
+// Unwrap returns the conversion error.
+func (self *ValueError) Unwrap() error { return self.Err }
+
+// argumentError returns a new *ParseError of kind described by err caused by
+// the argument at index in arguments being parsed by self and option, if
+// not nil.
+func (self *Config) argumentError(kind error, index int, option *Option, err error) *ParseError {
+	var out = &ParseError{
+		Kind:     kind,
+		Index:    -1,
+		Option:   option,
+		Commands: slices.Clone(self.chain),
+		Err:      err,
+		args:     self.argv,
+	}
+	if index >= 0 && index < len(self.argv) {
+		out.Index, out.Arg = index, self.argv[index]
+	}
+	return out
+}
+
+// argIndex returns the index of the current argument in arguments being
+// parsed by self.
+func (self *Config) argIndex() int { return len(self.argv) - len(self.Args) }
+
+// collect appends err to errors collected by self and returns nil if
+// [Config.CollectErrors] is enabled, otherwise returns err. Collected errors
+// are prefixed with their origin in response files, if any.
+func (self *Config) collect(err error) error {
+	if err == nil || !self.CollectErrors {
+		return err
+	}
+	self.errs = append(self.errs, self.originError(err))
+	return nil
+}
+
+// collected returns errors collected by self joined with err, if not nil.
+// It returns nil if there are no errors and the error itself if there is
+// only one.
+func (self *Config) collected(err error) error {
+	var errs = self.errs
+	if err != nil {
+		errs = append(errs, err)
+	}
+	if len(errs) == 1 {
+		return errs[0]
+	}
+	return errors.Join(errs...)
+}
+
+// optionError returns a new *ParseError of kind described by err caused by
+// option, unrelated to any argument, in the chain of commands.
+func optionError(kind error, option *Option, commands []*Command, err error) *ParseError {
+	return &ParseError{
+		Kind:     kind,
+		Index:    -1,
+		Option:   option,
+		Commands: slices.Clone(commands),
+		Err:      err,
+	}
+}
+
+// validationErrorf returns a new *ParseError of kind [ErrInvalidConfig] for
+// option, if not nil, with a message formatted from format and args.
+func validationErrorf(option *Option, format string, args ...any) *ParseError {
+	return optionError(ErrInvalidConfig, option, nil, fmt.Errorf(format, args...))
+}
+
+// prependCommand prepends command to Commands of err if err is a *ParseError
+// and returns err.
+func prependCommand(err error, command *Command) error {
+	if pe, ok := err.(*ParseError); ok {
+		pe.Commands = append([]*Command{command}, pe.Commands...)
+	}
+	return err
+}
@@ -7,6 +7,7 @@ package cmdline
 import (
 	"errors"
 	"fmt"
+	"strings"
 )
 
 // ErrHelp is returned by the HelpCommand handler on successfull invocation.
@@ -43,6 +44,11 @@ Usage:
 					PrintOptions(config.GetOutput(), config, config.Globals, 2)
 					fmt.Fprintf(config.GetOutput(), "\n")
 				}
+				if len(config.GlobalConstraints) > 0 {
+					fmt.Fprintf(config.GetOutput(), "Global option constraints are:\n\n")
+					PrintConstraints(config.GetOutput(), config, config.GlobalConstraints, 2)
+					fmt.Fprintf(config.GetOutput(), "\n")
+				}
 				if len(topicMap) > 0 {
 					fmt.Fprintf(config.GetOutput(), "Available topics are:\n\n")
 					for topic := range topicMap {
@@ -65,7 +71,10 @@ Usage:
 				}
 			}
 
-			var cmd *Command
+			var (
+				cmd  *Command
+				path []*Command
+			)
 			for cmds := config.Commands; cmds != nil; {
 				if cmd = cmds.Find(vals[0]); cmd == nil {
 					return fmt.Errorf("Command '%s' not found.", vals[0])
@@ -73,6 +82,7 @@ Usage:
 				vals = vals[1:]
 
 				if len(vals) > 0 {
+					path = append(path, cmd)
 					cmds = cmd.SubCommands
 					continue
 				}
@@ -82,12 +92,17 @@ Usage:
 				} else {
 					fmt.Fprintf(config.GetOutput(), "%s\n", cmd.Doc)
 				}
+				if len(cmd.Aliases) > 0 {
+					fmt.Fprintf(config.GetOutput(), "\nAliases: %s\n", strings.Join(cmd.Aliases, ", "))
+				}
 
 				var (
-					showOpts = cmd.Options.Count() > 0
-					showCmds = cmd.SubCommands.Count() > 0
+					inherited     = persistentOptions(config, path)
+					showOpts      = cmd.Options.Count() > 0
+					showInherited = inherited.Count() > 0
+					showCmds      = cmd.SubCommands.Count() > 0
 				)
-				if showOpts || showCmds {
+				if showOpts || showInherited || showCmds {
 					fmt.Fprintf(config.GetOutput(), "\n")
 				}
 				if showOpts {
@@ -95,6 +110,16 @@ Usage:
 					PrintOptions(config.GetOutput(), config, cmd.Options, 2)
 					fmt.Fprintf(config.GetOutput(), "\n")
 				}
+				if len(cmd.Constraints) > 0 {
+					fmt.Fprintf(config.GetOutput(), "Command option constraints are:\n\n")
+					PrintConstraints(config.GetOutput(), config, cmd.Constraints, 2)
+					fmt.Fprintf(config.GetOutput(), "\n")
+				}
+				if showInherited {
+					fmt.Fprintf(config.GetOutput(), "Inherited options are:\n\n")
+					PrintOptions(config.GetOutput(), config, inherited, 2)
+					fmt.Fprintf(config.GetOutput(), "\n")
+				}
 				if showCmds {
 					fmt.Fprintf(config.GetOutput(), "Available sub commands are:\n\n")
 					PrintCommandsGroup(config.GetOutput(), config, cmd.SubCommands, 2)
@@ -4,6 +4,12 @@
 
 package cmdline
 
+import (
+	"slices"
+	"strconv"
+	"strings"
+)
+
 // Kind specifies the kind of an Option.
 //
 // It defines the Option behaviour and how it parses its arguments.
@@ -17,6 +23,11 @@ const (
 	//
 	// A Boolean option is an option that takes no arguments. It is marked as
 	// parsed if it was specified in command arguments.
+	//
+	// If [Config.UseAssignment] is enabled it may be assigned an explicit
+	// "true" or "false" value and if [Option.Negatable] is set it may be
+	// switched off using the [NegationPrefix]. In both cases the value is
+	// stored in Option's Values.
 	Boolean
 
 	// Optional is an optional Option.
@@ -75,6 +86,41 @@ const (
 	//
 	// There may only be a single variadic option in [Options].
 	Variadic
+
+	// Counted is a counted Option.
+	//
+	// A Counted option takes no arguments and may be specified multiple
+	// times, including combined short form, e.g. '-vvv'. It counts the number
+	// of times it was specified and stores the count as a single decimal
+	// value in Option's Values so it maps to integer variables.
+	//
+	// If [Config.UseAssignment] is enabled the count may be assigned directly,
+	// e.g. '--verbose=3'.
+	Counted
+
+	// KeyValue is a key-value Option.
+	//
+	// A KeyValue option takes a value in '<key>=<value>' form and may be
+	// specified multiple times, e.g. '-D name=value -D other=x'. Each value
+	// is appended to Option's Values as given. The key and value are split
+	// on [Option.KeySeparator] and keys given multiple times are handled as
+	// specified by [Option.DuplicateKeys].
+	//
+	// It maps to a pointer to a map with keys and values of any type
+	// supported by [Option.Var], e.g. '*map[string]string'.
+	KeyValue
+
+	// OptionalValue is an Option with an optional value.
+	//
+	// It may be specified bare, in which case it takes the value of
+	// [Option.ImplicitValue], or with an explicit value, e.g. '--color' or
+	// '--color=always'.
+	//
+	// An explicit value may be assigned to its long name regardless of
+	// [Config.UseAssignment]. If it is disabled the argument following the
+	// option is also taken as its value unless it is an option, the
+	// terminator or a name of a command, e.g. '--color always'.
+	OptionalValue
 )
 
 // String implements [fmt.Stringer] on Kind.
@@ -92,11 +138,61 @@ func (self Kind) String() string {
 		return "Indexed"
 	case Variadic:
 		return "Variadic"
+	case Counted:
+		return "Counted"
+	case KeyValue:
+		return "KeyValue"
+	case OptionalValue:
+		return "OptionalValue"
 	default:
 		return "[INVALID]"
 	}
 }
 
+// Source specifies where the value of an [Option] came from.
+type Source int
+
+const (
+	// SourceNone indicates that the Option was not set.
+	SourceNone Source = iota
+	// SourceArguments indicates that the Option was parsed from arguments.
+	SourceArguments
+	// SourceEnvironment indicates that the Option value was read from the
+	// environment variable named by [Option.EnvVar].
+	SourceEnvironment
+	// SourceDefault indicates that the Option value was set from
+	// [Option.Default].
+	SourceDefault
+)
+
+// String implements [fmt.Stringer] on Source.
+func (self Source) String() string {
+	switch self {
+	case SourceArguments:
+		return "arguments"
+	case SourceEnvironment:
+		return "environment"
+	case SourceDefault:
+		return "default"
+	default:
+		return "none"
+	}
+}
+
+// DuplicateKeys specifies how a [KeyValue] Option handles a key that was
+// given multiple times.
+type DuplicateKeys int
+
+const (
+	// LastKeyWins keeps the last value given to a key.
+	LastKeyWins DuplicateKeys = iota
+	// FirstKeyWins keeps the first value given to a key and ignores others.
+	FirstKeyWins
+	// RejectDuplicateKeys makes Parse return an error if a key was given
+	// multiple times.
+	RejectDuplicateKeys
+)
+
 // Option defines an option.
 //
 // Several option types exist and define how option is parsed. For details see 
@@ -138,17 +234,158 @@ type Option struct {
 	// An argument with a short prefix is matched against this property.
 	ShortName string
 
+	// LongAliases are optional alternative long names of the Option, e.g.
+	// 'out' for 'output'.
+	//
+	// They follow the same rules as [Option.LongName] and must be unique
+	// among all long names and long aliases in Options. They may be used in
+	// place of LongName everywhere an Option is addressed by long name.
+	LongAliases []string
+
+	// ShortAliases are optional alternative short names of the Option.
+	//
+	// They follow the same rules as [Option.ShortName] and must be unique
+	// among all short names and short aliases in Options.
+	ShortAliases []string
+
 	// Help is the option help text.
 	//
 	// It should be a short, single line description of the option.
 	Help string
 
-	// IsParsed indicates if the Option was parsed from arguments.
+	// Persistent if true makes the Option available to all sub commands of
+	// the [Command] that defines it, recursively. If the Option is defined in
+	// [Config.Globals] it is available to all commands.
+	//
+	// A Persistent Option may be specified in arguments of the defining
+	// Command or following any of its sub commands, e.g.
+	// 'prog items add --verbose' where 'verbose' is defined on 'items'.
+	// Options of a sub command take precedence over inherited Persistent
+	// Options with the same name, then the nearest parent wins.
+	//
+	// Only named options, i.e. not [Indexed] or [Variadic], may be Persistent.
+	Persistent bool
+
+	// Negatable if true allows a [Boolean] option to be specified with its
+	// long name prefixed with [NegationPrefix] in which case it is parsed
+	// with a "false" value, e.g. '--no-color'.
+	//
+	// A Boolean option given a false value, either negated or assigned, e.g.
+	// '--color=false', is parsed but is not considered given by
+	// [Context.Parsed], [Options.Parsed], [ExclusivityGroups] and
+	// [Constraints].
+	//
+	// It is ignored for other option kinds.
+	Negatable bool
+
+	// EnvVar is an optional name of an environment variable from which the
+	// Option value is read if the Option was not specified in arguments,
+	// e.g. 'APP_TOKEN'. Unset and empty variables are ignored.
+	//
+	// The value is read after all arguments have been parsed and is set as
+	// if it was given to the Option in arguments, which satisfies [Required]
+	// options. Boolean options require a value parseable as a bool and
+	// Counted options a count.
+	//
+	// Values read from environment do not conflict with options given in
+	// arguments in [ExclusivityGroups] or [ExactlyOne] constraints; options
+	// given in arguments take precedence.
+	//
+	// Only named options, i.e. not [Indexed] or [Variadic], may use EnvVar.
+	EnvVar string
+
+	// Default is an optional default value of the Option in string form,
+	// e.g. '30s'.
+	//
+	// If not empty it is set as Option's Values and converted to the mapped
+	// variable if the Option was neither parsed from arguments nor read from
+	// environment. The Option is not marked as parsed and its
+	// [Option.Source] is set to [SourceDefault].
+	//
+	// [Required] and [Indexed] options may not have a Default.
+	Default string
+
+	// Choices is an optional set of allowed values of the Option, e.g.
+	// 'json', 'yaml' and 'text' for a format option.
+	//
+	// If not empty, each value given to the Option from arguments,
+	// environment or Default must be one of Choices or Parse returns an error
+	// listing valid choices. Choices are listed in help output and may be
+	// used by completion generators.
+	//
+	// [Boolean], [Counted] and [KeyValue] options may not have Choices.
+	Choices []string
+
+	// Validators are optional validators of Option's values, e.g. a
+	// [Range] for a port number.
+	//
+	// Each value given to the Option from arguments, environment or Default
+	// is validated by all Validators, in order, before it is converted to
+	// [Option.Var]. Parse returns an error naming the Option and the value
+	// if validation fails. Descriptions of Validators are listed in help
+	// output.
+	//
+	// [Boolean] and [Counted] options may not have Validators.
+	Validators []Validator
+
+	// KeySeparator separates the key from the value in values of a
+	// [KeyValue] Option. It is optional and is defaulted to
+	// DefaultKeySeparator if left empty.
+	KeySeparator string
+
+	// DuplicateKeys specifies how a [KeyValue] Option handles a key that was
+	// given multiple times.
+	//
+	// Default: LastKeyWins
+	DuplicateKeys DuplicateKeys
+
+	// ImplicitValue is the value of an [OptionalValue] Option specified
+	// without a value. It is required for OptionalValue options.
+	ImplicitValue string
+
+	// Arity is the number of values an [Optional], [Required] or
+	// [Repeated] Option takes each time it is specified, e.g. 2 for
+	// '--resize 800 600'. Zero and one both mean a single value.
+	//
+	// If [Config.UseAssignment] is disabled the Option consumes Arity
+	// following arguments. Otherwise the values are given in a single
+	// argument, separated by [Option.Separator] or DefaultAritySeparator if
+	// no Separator is set, e.g. '--resize=800,600'. Parse returns an error
+	// if the number of values given differs from Arity.
+	//
+	// Values may be mapped to a Var that is a pointer to a slice, an array
+	// or a struct, in which case values are set to its elements or exported
+	// fields in order.
+	Arity int
+
+	// Layout is an optional layout of [time.Time] values of the Option as
+	// accepted by time.Parse, e.g. '2006-01-02'. It is defaulted to
+	// time.RFC3339 if left empty.
+	Layout string
+
+	// Separator is an optional separator of multiple values given in a
+	// single argument to a [Repeated] or a [Variadic] Option, e.g. ',' for
+	// '--tags=a,b,c'. Each separated value is added to Option's Values and
+	// multiple occurrences may be combined, e.g. '--tags=a,b --tags=c'.
+	//
+	// Separators inside double quoted segments are ignored and quotes are
+	// removed from segments, e.g. '"a,b",c' yields 'a,b' and 'c'. Values
+	// read from environment or Default are separated as well.
+	//
+	// Only Repeated and Variadic options or options with an [Option.Arity]
+	// greater than one may have a Separator.
+	Separator string
+
+	// IsParsed indicates if the Option was parsed from arguments or read from
+	// environment. See [Option.Source].
 	//
 	// For Repeated Options it indicates that the Option was parsed at least
 	// once.
 	IsParsed bool
 
+	// Source specifies where the Option value came from.
+	Source Source
+
 	// Kind is the kind of option which determines how the Option parses
 	// its arguments.
 	//
@@ -163,13 +400,70 @@ type Option struct {
 	// Var is an optional pointer to a variable that will be set from Option
 	// argument(s).
 	//
-	// Only basic types are supported and a slice of string.
+	// Supported are pointers to:
+	//
+	//   - basic types and types defined on them, where integers may be given
+	//     with a '0x', '0o' or '0b' prefix,
+	//   - [time.Duration], [time.Time] (see [Option.Layout]), [ByteSize],
+	//     [os.FileMode] given in octal, [net.IP], [net.IPNet], [netip.Addr],
+	//     [netip.Prefix], [url.URL] and [regexp.Regexp],
+	//   - types implementing [Value] or [encoding.TextUnmarshaler],
+	//   - slices of the above, to which each value is appended, for
+	//     [Repeated] and [Variadic] options,
+	//   - arrays and structs of the above, one value per element or exported
+	//     field, for options with an [Option.Arity],
+	//   - maps with keys and values of the above for [KeyValue] options.
+	//
+	// A pointer to a pointer to a supported type, e.g. '**int', is set to a
+	// newly allocated variable only if the Option was given a value, from
+	// arguments, environment or Default, and is left nil otherwise. This
+	// distinguishes an omitted option from one given a zero value.
 	Var any
 }
 
+// LongNames returns the Option long name followed by its long aliases.
+func (self *Option) LongNames() []string {
+	return append([]string{self.LongName}, self.LongAliases...)
+}
+
+// ShortNames returns the Option short name, if set, followed by its short
+// aliases.
+func (self *Option) ShortNames() (out []string) {
+	if self.ShortName != "" {
+		out = append(out, self.ShortName)
+	}
+	return append(out, self.ShortAliases...)
+}
+
+// HasLongName returns true if name equals Option long name or one of its
+// long aliases.
+func (self *Option) HasLongName(name string) bool {
+	return self.LongName == name || slices.Contains(self.LongAliases, name)
+}
+
+// HasShortName returns true if name equals Option short name or one of its
+// short aliases.
+func (self *Option) HasShortName(name string) bool {
+	return self.ShortName == name || slices.Contains(self.ShortAliases, name)
+}
+
+// isSet returns true if the Option was parsed and, if it is a [Boolean]
+// Option, was not last given a false value, e.g. '--no-color'.
+func (self *Option) isSet() bool {
+	if !self.IsParsed {
+		return false
+	}
+	if self.Kind == Boolean && len(self.Values) > 0 {
+		var b, err = strconv.ParseBool(self.Values[len(self.Values)-1])
+		return err != nil || b
+	}
+	return true
+}
+
 // Reset resets the Option to initial state. It does not modify linked variable.
 func (self *Option) Reset() {
 	self.IsParsed = true
+	self.Source = SourceNone
 	clear(self.Values)
 }
 
@@ -225,6 +519,22 @@ func (self *Options) Boolean(longName, shortName, help string) *Options {
 	return self.BooleanVar(longName, shortName, help, nil)
 }
 
+// Counted registers a new counted option in self and returns self.
+func (self *Options) Counted(longName, shortName, help string) *Options {
+	return self.CountedVar(longName, shortName, help, nil)
+}
+
+// KeyValue registers a new key-value option in self and returns self.
+func (self *Options) KeyValue(longName, shortName, help string) *Options {
+	return self.KeyValueVar(longName, shortName, help, nil)
+}
+
+// OptionalValue registers a new option with an optional value in self that
+// takes implicitValue if specified without a value and returns self.
+func (self *Options) OptionalValue(longName, shortName, help, implicitValue string) *Options {
+	return self.OptionalValueVar(longName, shortName, help, implicitValue, nil)
+}
+
 // Optional registers a new optional option in self and returns self.
 func (self *Options) Optional(longName, shortName, help string) *Options {
 	return self.OptionalVar(longName, shortName, help, nil)
@@ -261,6 +571,41 @@ func (self *Options) BooleanVar(longName, shortName, help string, v any) *Option
 	})
 }
 
+// Counted registers a new counted option in self and returns self.
+func (self *Options) CountedVar(longName, shortName, help string, v any) *Options {
+	return self.Register(&Option{
+		LongName:  longName,
+		ShortName: shortName,
+		Help:      help,
+		Kind:      Counted,
+		Var:       v,
+	})
+}
+
+// KeyValue registers a new key-value option in self and returns self.
+func (self *Options) KeyValueVar(longName, shortName, help string, v any) *Options {
+	return self.Register(&Option{
+		LongName:  longName,
+		ShortName: shortName,
+		Help:      help,
+		Kind:      KeyValue,
+		Var:       v,
+	})
+}
+
+// OptionalValue registers a new option with an optional value in self that
+// takes implicitValue if specified without a value and returns self.
+func (self *Options) OptionalValueVar(longName, shortName, help, implicitValue string, v any) *Options {
+	return self.Register(&Option{
+		LongName:      longName,
+		ShortName:     shortName,
+		Help:          help,
+		Kind:          OptionalValue,
+		ImplicitValue: implicitValue,
+		Var:           v,
+	})
+}
+
 // Optional registers a new optional option in self and returns self.
 func (self *Options) OptionalVar(longName, shortName, help string, v any) *Options {
 	return self.Register(&Option{
@@ -316,42 +661,61 @@ func (self *Options) VariadicVar(name, help string, v any) *Options {
 	})
 }
 
-// FindLong returns an Option with given longName or nil if not found.
+// FindLong returns an Option with given longName or long alias or nil if not
+// found.
 func (self Options) FindLong(longName string) *Option {
 	for i := 0; i < len(self); i++ {
-		if self[i].LongName == longName {
+		if self[i].HasLongName(longName) {
 			return self[i]
 		}
 	}
 	return nil
 }
 
-// Get returns an Option with given shortName or nil if not found.
+// MatchLong returns Options whose long names or long aliases start with
+// prefix. If an Option with long name or long alias equal to prefix exists
+// only that Option is returned.
+func (self Options) MatchLong(prefix string) (out Options) {
+	if option := self.FindLong(prefix); option != nil {
+		return Options{option}
+	}
+	for _, option := range self {
+		for _, name := range option.LongNames() {
+			if strings.HasPrefix(name, prefix) {
+				out = append(out, option)
+				break
+			}
+		}
+	}
+	return
+}
+
+// Get returns an Option with given shortName or short alias or nil if not
+// found.
 func (self Options) FindShort(shortName string) *Option {
 	for i := 0; i < len(self); i++ {
-		if self[i].ShortName == shortName {
+		if self[i].HasShortName(shortName) {
 			return self[i]
 		}
 	}
 	return nil
 }
 
-// IsParsed implements Context.IsParsed.
+// Parsed returns true if an Option with longName was parsed, the same as
+// [Context.Parsed]. It returns false for a [Boolean] Option given a false
+// value, e.g. '--no-color' or '--color=false'.
 func (self Options) Parsed(longName string) bool {
-	for _, v := range self {
-		if v.LongName == longName {
-			return v.IsParsed
-		}
+	if v := self.FindLong(longName); v != nil {
+		return v.isSet()
 	}
 	return false
 }
 
-// Parsed implements Context.RawValues.
+// Values returns Values of an Option with longName, the same as
+// [Context.Values].
 func (self Options) Values(longName string) Values {
-	for _, v := range self {
-		if v.LongName == longName {
-			return v.Values
-		}
+	if v := self.FindLong(longName); v != nil {
+		return v.Values
 	}
 	return nil
 }
@@ -8,6 +8,12 @@ import (
 	"encoding"
 	"errors"
 	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"reflect"
+	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -26,6 +32,10 @@ func (self Args) Kind(config *Config) (kind Argument) {
 	if self.Eof() {
 		return NoArgument
 	}
+	// everything after a terminator is text.
+	if config.terminated {
+		return TextArgument
+	}
 	kind = TextArgument
 	// in case of "-" as short and "--" as long, long wins.
 	if strings.HasPrefix(self.First(), config.ShortPrefix) {
@@ -91,30 +101,36 @@ func (self Args) Eof() bool { return len(self) == 0 }
 
 // parse parses [Commands] from config.Args or returns an error.
 func (self Commands) parse(config *Config) (err error) {
+	if config.terminated && !config.Args.Eof() {
+		return config.argumentError(ErrUnexpectedArgument, config.argIndex(), nil,
+			fmt.Errorf("unexpected argument '%s'", config.Args.First()))
+	}
 	switch kind, name := config.Args.Kind(config), config.Args.Text(config); kind {
 	case NoArgument:
 		return nil
 	case LongArgument, ShortArgument:
-		return errors.New("expected command name, got option")
+		return config.argumentError(ErrUnexpectedArgument, config.argIndex(), nil,
+			errors.New("expected command name, got option"))
 	case TextArgument:
-		var cmd = self.Find(name)
+		var cmd *Command
+		if cmd, err = self.find(config, name); err != nil {
+			return config.argumentError(ErrAmbiguous, config.argIndex(), nil, err)
+		}
 		if cmd == nil {
-			return fmt.Errorf("command '%s' not registered", name)
+			return config.argumentError(ErrUnknownCommand, config.argIndex(), nil, unknownCommand(self, name))
 		}
 		config.Args.Next()
+		config.chain = append(config.chain, cmd)
 		if err = cmd.Options.parse(config); err != nil {
 			return
 		}
-		if err = validateCommandExclusivityGroups(cmd); err != nil {
-			return
-		}
 		cmd.executed = true
-		config.chain = append(config.chain, cmd)
 		if err = cmd.SubCommands.parse(config); err != nil {
 			return
 		}
 		if cmd.RequireSubExecution && cmd.SubCommands.Count() > 0 && !cmd.SubCommands.AnyExecuted() {
-			return fmt.Errorf("command '%s' requires execution of one of its subcommands", cmd.Name)
+			return config.argumentError(ErrMissingCommand, config.argIndex(), nil,
+				fmt.Errorf("command '%s' requires execution of one of its subcommands", cmd.Name))
 		}
 	}
 	return nil
@@ -123,25 +139,44 @@ func (self Commands) parse(config *Config) (err error) {
 // parse parses [Options] from config.Args or returns an error.
 func (self Options) parse(config *Config) (err error) {
 
-	if self.Count() == 0 {
+	// With no options to parse only consume the terminator.
+	if self.Count() == 0 && persistentOptions(config, config.chain).Count() == 0 {
+		if !config.terminated && !config.Args.Eof() && config.Args.First() == config.Terminator {
+			config.terminated = true
+			config.Args.Next()
+		}
 		return nil
 	}
 
 	var (
 		opt        *Option
 		key, val   string
+		raw        string
 		assignment bool
+		negated    bool
 		combined   string
 	)
 
 	for !config.Args.Eof() {
 
+		// Detect the terminator, unless an option is waiting for a value.
+		if opt == nil && !config.terminated && config.Args.First() == config.Terminator {
+			config.terminated = true
+			config.Args.Next()
+			continue
+		}
+
 		// Parse key and val.
-		if config.UseAssignment {
+		if config.terminated {
+			key = config.Args.First()
+			val = ""
+			assignment = false
+		} else if config.UseAssignment {
 			key, val, assignment = strings.Cut(config.Args.Text(config), "=")
 			key = strings.TrimSpace(key)
+			raw = strings.TrimSpace(val)
 			if assignment && val != "" {
-				val, _ = strutils.UnquoteDouble(strings.TrimSpace(val))
+				val, _ = strutils.UnquoteDouble(raw)
 			}
 		} else {
 			key = strings.TrimSpace(config.Args.Text(config))
@@ -151,14 +186,16 @@ func (self Options) parse(config *Config) (err error) {
 
 		// Try to detect the option by argument kind.
 		// If not prefixed see if theres defined and yet unparsed indexed.
-		// If prefixed see if its Boolean, Optional, Required or Repeated.
+		// If prefixed see if its Boolean, Optional, Required, Repeated or
+		// KeyValue.
 		switch kind := config.Args.Kind(config); kind {
 		case TextArgument:
 			if !config.UseAssignment && opt != nil {
 				switch opt.Kind {
-				case Optional, Required, Repeated:
+				case Optional, Required, Repeated, KeyValue, OptionalValue:
 				default:
-					return fmt.Errorf("option '%s' requires a value", opt.LongName)
+					return config.argumentError(ErrMissingValue, config.argIndex()-1, opt,
+						fmt.Errorf("option '%s' requires a value", opt.LongName))
 
 				}
 			} else {
@@ -169,70 +206,120 @@ func (self Options) parse(config *Config) (err error) {
 		case LongArgument:
 			if config.IndexedFirst {
 				if fui := self.getFirstUnparsedIndexed(); fui != nil {
-					return fmt.Errorf("indexed argument '%s' not parsed", fui.LongName)
+					return config.argumentError(ErrMissingOption, config.argIndex(), fui,
+						fmt.Errorf("indexed argument '%s' not parsed", fui.LongName))
 				}
 			}
 
 			if !config.UseAssignment && opt != nil {
-				return fmt.Errorf("option '%s' requires a value", opt.LongName)
+				return config.argumentError(ErrMissingValue, config.argIndex()-1, opt,
+					fmt.Errorf("option '%s' requires a value", opt.LongName))
 			}
 
-			if opt = self.FindLong(key); opt == nil {
-				return fmt.Errorf("unknown option '%s'", key)
+			if opt, negated, err = self.lookupLong(config, key); err != nil {
+				return config.argumentError(ErrAmbiguous, config.argIndex(), nil, err)
+			}
+
+			// An OptionalValue option may be assigned a value in any mode.
+			if name, value, ok := strings.Cut(key, "="); opt == nil && ok && !config.UseAssignment {
+				if o, _, _ := self.lookupLong(config, strings.TrimSpace(name)); o != nil && o.Kind == OptionalValue {
+					opt, assignment = o, true
+					if raw, val = strings.TrimSpace(value), ""; raw != "" {
+						val, _ = strutils.UnquoteDouble(raw)
+					}
+				}
+			}
+			if opt == nil {
+				if err = config.collect(config.argumentError(ErrUnknownOption, config.argIndex(), nil,
+					unknownOption(slices.Concat(self, persistentOptions(config, config.chain)), key, true))); err != nil {
+					return
+				}
+				config.Args.Next()
+				continue
 			}
 
 			switch opt.Kind {
-			case Boolean:
-			case Optional, Required, Repeated:
+			case Boolean, Counted:
+			case Optional, Required, Repeated, KeyValue:
 				if !config.UseAssignment {
 					config.Args.Next()
 					continue
 				}
+			case OptionalValue:
+				if !config.UseAssignment && !assignment && nextIsValue(config) {
+					config.Args.Next()
+					continue
+				}
 			default:
-				return fmt.Errorf("option '%s' exists, but is not named", opt.LongName)
+				return config.argumentError(ErrUnexpectedArgument, config.argIndex(), opt,
+					fmt.Errorf("option '%s' exists, but is not named", opt.LongName))
 
 			}
 		case ShortArgument:
 
 			if config.IndexedFirst {
 				if fui := self.getFirstUnparsedIndexed(); fui != nil {
-					return fmt.Errorf("indexed argument '%s' not parsed", fui.LongName)
+					return config.argumentError(ErrMissingOption, config.argIndex(), fui,
+						fmt.Errorf("indexed argument '%s' not parsed", fui.LongName))
 				}
 			}
 
 			if !config.UseAssignment && opt != nil {
-				return fmt.Errorf("option '%s' requires a value", opt.LongName)
+				return config.argumentError(ErrMissingValue, config.argIndex()-1, opt,
+					fmt.Errorf("option '%s' requires a value", opt.LongName))
 			}
 
 			// Set up for combined booleans parsing.
 			if len(key) > 1 {
 				combined = key
 				for _, k := range combined {
-					if opt = self.FindShort(string(k)); opt == nil {
-						return fmt.Errorf("combined argument %s refers to unknown option %s", combined, string(k))
+					if opt = self.lookupShort(config, string(k)); opt == nil {
+						err = config.argumentError(ErrUnknownOption, config.argIndex(), nil,
+							unknownOption(slices.Concat(self, persistentOptions(config, config.chain)), string(k), false))
+						break
+					}
+					if opt.Kind != Boolean && opt.Kind != Counted {
+						return config.argumentError(ErrUnexpectedArgument, config.argIndex(), opt,
+							fmt.Errorf("combined argument %s may contain boolean or counted options only", combined))
 					}
-					if opt.Kind != Boolean {
-						return fmt.Errorf("combined argument %s may contain boolean options only", combined)
+				}
+				if opt == nil {
+					if err = config.collect(err); err != nil {
+						return
 					}
+					combined = ""
+					config.Args.Next()
+					continue
 				}
-				opt = self.FindShort(combined[:1])
+				opt = self.lookupShort(config, combined[:1])
 				combined = combined[1:]
 				goto ParseOption
 			}
 
-			if opt = self.FindShort(key); opt == nil {
-				return fmt.Errorf("unknown option '%s'", key)
+			if opt = self.lookupShort(config, key); opt == nil {
+				if err = config.collect(config.argumentError(ErrUnknownOption, config.argIndex(), nil,
+					unknownOption(slices.Concat(self, persistentOptions(config, config.chain)), key, false))); err != nil {
+					return
+				}
+				config.Args.Next()
+				continue
 			}
 
 			switch opt.Kind {
-			case Boolean:
-			case Optional, Required, Repeated:
+			case Boolean, Counted:
+			case Optional, Required, Repeated, KeyValue:
 				if !config.UseAssignment {
 					config.Args.Next()
 					continue
 				}
+			case OptionalValue:
+				if !config.UseAssignment && nextIsValue(config) {
+					config.Args.Next()
+					continue
+				}
 			default:
-				return fmt.Errorf("option '%s' exists, but is not named", opt.LongName)
+				return config.argumentError(ErrUnexpectedArgument, config.argIndex(), opt,
+					fmt.Errorf("option '%s' exists, but is not named", opt.LongName))
 
 			}
 		}
@@ -251,58 +338,137 @@ func (self Options) parse(config *Config) (err error) {
 		}
 
 		// Fail if non *Repeatable option and parsed multiple times.
-		if opt.Kind != Repeated {
+		if opt.Kind != Repeated && opt.Kind != Counted && opt.Kind != KeyValue {
 			if opt.IsParsed {
-				return fmt.Errorf("option %s specified multiple times", opt.LongName)
+				return config.argumentError(ErrDuplicateOption, config.argIndex(), opt,
+					fmt.Errorf("option %s specified multiple times", opt.LongName))
 			}
 		}
 
 	ParseOption:
 
+		// Index of the first value added by this argument and the index of
+		// the argument. Terminator is the index of the value that followed a
+		// terminator consumed by a Variadic option, if any.
+		var from, index, terminator = len(opt.Values), config.argIndex(), -1
+
 		// Set Option as parsed.
 		switch opt.Kind {
 		case Boolean:
-			if !config.UseAssignment {
-				opt.IsParsed = true
-			} else {
+			if negated {
 				if assignment {
-					return fmt.Errorf("option '%s' cannot be assigned a value", opt.LongName)
+					return config.argumentError(ErrInvalidValue, config.argIndex(), opt,
+						fmt.Errorf("negated option '%s' cannot be assigned a value", opt.LongName))
 				}
-				opt.IsParsed = true
+				opt.Values = append(opt.Values, "false")
+			} else if assignment {
+				var b bool
+				if b, err = strconv.ParseBool(val); err != nil {
+					err = config.argumentError(ErrInvalidValue, config.argIndex(), opt,
+						fmt.Errorf("option '%s' requires a boolean value", opt.LongName))
+					break
+				}
+				opt.Values = append(opt.Values, strconv.FormatBool(b))
+			}
+			opt.IsParsed = true
+		case Counted:
+			var count int
+			if assignment {
+				if count, err = strconv.Atoi(val); err != nil || count < 0 {
+					err = config.argumentError(ErrInvalidValue, config.argIndex(), opt,
+						fmt.Errorf("option '%s' requires a non-negative count", opt.LongName))
+					break
+				}
+			} else {
+				count, _ = strconv.Atoi(opt.Values.First())
+				count++
 			}
+			opt.Values = Values{strconv.Itoa(count)}
+			opt.IsParsed = true
 		case Optional:
+			var values Values
 			if !config.UseAssignment {
-				opt.Values = append(opt.Values, key)
+				if values, err = argumentValues(config, opt, key, key); err != nil {
+					return
+				}
+				opt.Values = append(opt.Values, values...)
 				opt.IsParsed = true
 			} else {
 				if !assignment || val == "" {
-					return fmt.Errorf("option '%s' requires a value", opt.LongName)
+					return config.argumentError(ErrMissingValue, config.argIndex(), opt,
+						fmt.Errorf("option '%s' requires a value", opt.LongName))
 				}
-				opt.Values = append(opt.Values, val)
+				if values, err = argumentValues(config, opt, raw, val); err != nil {
+					return
+				}
+				opt.Values = append(opt.Values, values...)
 				opt.IsParsed = true
 			}
+		case OptionalValue:
+			var value = opt.ImplicitValue
+			if assignment {
+				if val == "" {
+					return config.argumentError(ErrMissingValue, config.argIndex(), opt,
+						fmt.Errorf("option '%s' requires a value", opt.LongName))
+				}
+				value = val
+			} else if !config.UseAssignment && config.Args.Kind(config) == TextArgument {
+				value = key
+			}
+			opt.Values = append(opt.Values, value)
+			opt.IsParsed = true
 		case Required:
+			var values Values
 			if !config.UseAssignment {
-				opt.Values = append(opt.Values, key)
+				if values, err = argumentValues(config, opt, key, key); err != nil {
+					return
+				}
+				opt.Values = append(opt.Values, values...)
 				opt.IsParsed = true
 			} else {
 				if !assignment || val == "" {
-					return fmt.Errorf("option '%s' requires a value", opt.LongName)
+					return config.argumentError(ErrMissingValue, config.argIndex(), opt,
+						fmt.Errorf("option '%s' requires a value", opt.LongName))
 				}
-				opt.Values = append(opt.Values, val)
+				if values, err = argumentValues(config, opt, raw, val); err != nil {
+					return
+				}
+				opt.Values = append(opt.Values, values...)
 				opt.IsParsed = true
 			}
 		case Repeated:
+			var values Values
 			if !config.UseAssignment {
-				opt.Values = append(opt.Values, key)
+				if values, err = argumentValues(config, opt, key, key); err != nil {
+					return
+				}
+				opt.Values = append(opt.Values, values...)
 				opt.IsParsed = true
 			} else {
 				if !assignment || val == "" {
-					return fmt.Errorf("option '%s' requires a value", opt.LongName)
+					return config.argumentError(ErrMissingValue, config.argIndex(), opt,
+						fmt.Errorf("option '%s' requires a value", opt.LongName))
+				}
+				if values, err = argumentValues(config, opt, raw, val); err != nil {
+					return
 				}
-				opt.Values = append(opt.Values, val)
+				opt.Values = append(opt.Values, values...)
 				opt.IsParsed = true
 			}
+		case KeyValue:
+			var value = key
+			if config.UseAssignment {
+				if !assignment || val == "" {
+					return config.argumentError(ErrMissingValue, config.argIndex(), opt,
+						fmt.Errorf("option '%s' requires a value", opt.LongName))
+				}
+				value = val
+			}
+			if err = addKeyValue(opt, value); err != nil {
+				err = config.argumentError(ErrInvalidValue, config.argIndex(), opt, err)
+				break
+			}
+			opt.IsParsed = true
 		case Indexed:
 			if !config.UseAssignment {
 				opt.Values = append(opt.Values, key)
@@ -312,41 +478,376 @@ func (self Options) parse(config *Config) (err error) {
 				opt.IsParsed = true
 			}
 		case Variadic:
-			opt.Values = append(opt.Values, config.Args...)
+			for _, arg := range config.Args {
+				if !config.terminated && arg == config.Terminator {
+					config.terminated, terminator = true, len(opt.Values)
+					continue
+				}
+				opt.Values = append(opt.Values, splitValues(opt, arg, arg)...)
+			}
 			opt.IsParsed = true
 			config.Args.Clear()
 		}
 
-		// Set [Option.Var] value.
-		if err = self.setVar(opt); err != nil {
-			return fmt.Errorf("invalid Var '%v' for option '%s': %w", opt.Var, opt.LongName, err)
+		// Check values against allowed choices and set [Option.Var] value.
+		if err == nil {
+			opt.Source = SourceArguments
+			if err = checkChoices(opt); err != nil {
+				err = config.argumentError(ErrInvalidValue, index, opt, err)
+			} else if err = validateValues(opt, opt.Values[from:]); err != nil {
+				err = config.argumentError(ErrInvalidValue, index, opt, err)
+			} else if err = self.setVar(opt, from); err != nil {
+				// Point to the failed argument of a Variadic option.
+				var ve *ValueError
+				if opt.Kind == Variadic && opt.Separator == "" && errors.As(err, &ve) {
+					if index += ve.Index - from; terminator >= 0 && ve.Index >= terminator {
+						index++
+					}
+				}
+				err = config.argumentError(ErrInvalidValue, index, opt,
+					fmt.Errorf("invalid Var '%v' for option '%s': %w", opt.Var, opt.LongName, err))
+			}
+		}
+		if err = config.collect(err); err != nil {
+			return
 		}
 
 		// Combined booleans loop.
 		if len(combined) > 0 {
-			opt = self.FindShort(combined[:1])
+			opt = self.lookupShort(config, combined[:1])
 			combined = combined[1:]
 			goto ParseOption
 		}
 
 		opt = nil
+		negated = false
 		config.Args.Next()
 	}
 
+	if opt != nil {
+		return config.argumentError(ErrMissingValue, config.argIndex()-1, opt,
+			fmt.Errorf("option '%s' requires a value", opt.LongName))
+	}
+
 	for _, opt = range self {
-		if !opt.IsParsed {
-			if opt.Kind == Required {
-				return fmt.Errorf("required option '%s' not parsed", opt.LongName)
+		if !opt.IsParsed && opt.Kind == Indexed {
+			if err = config.collect(config.argumentError(ErrMissingOption, config.argIndex(), opt,
+				fmt.Errorf("indexed option '%s' not parsed", opt.LongName))); err != nil {
+				return
 			}
-			if opt.Kind == Indexed {
-				return fmt.Errorf("indexed option '%s' not parsed", opt.LongName)
+		}
+	}
+
+	return
+}
+
+// validateParsed returns an error if a Required option in self, defined in
+// the chain of commands, was not parsed. Errors are collected by config if
+// it collects errors. It is called after all arguments have been parsed.
+func (self Options) validateParsed(config *Config, commands []*Command) error {
+	for _, opt := range self {
+		if !opt.IsParsed && opt.Kind == Required {
+			if err := config.collect(optionError(ErrMissingOption, opt, commands,
+				fmt.Errorf("required option '%s' not parsed", opt.LongName))); err != nil {
+				return err
 			}
 		}
 	}
+	return nil
+}
 
+// applyFallbacks sets values of options in self that were not parsed from
+// arguments from environment variables named by [Option.EnvVar] or from
+// [Option.Default], in that order, then sets their variables. Options are
+// defined in the chain of commands. Errors are collected by config if it
+// collects errors. It is called after all arguments have been parsed.
+func (self Options) applyFallbacks(config *Config, commands []*Command) (err error) {
+	for _, opt := range self {
+		if err = self.applyFallback(opt); err != nil {
+			if err = config.collect(optionError(ErrInvalidValue, opt, commands, err)); err != nil {
+				return
+			}
+		}
+	}
+	return nil
+}
+
+// applyFallback applies a fallback value to opt if it was not parsed.
+func (self Options) applyFallback(opt *Option) (err error) {
+	if opt.IsParsed {
+		return nil
+	}
+	if value := os.Getenv(opt.EnvVar); opt.EnvVar != "" && value != "" {
+		if opt.Values, err = fallbackValues(opt, value); err != nil {
+			return fmt.Errorf("option '%s' from environment variable '%s': %w", opt.LongName, opt.EnvVar, err)
+		}
+		opt.IsParsed = true
+		opt.Source = SourceEnvironment
+	} else if opt.Default != "" {
+		if opt.Values, err = fallbackValues(opt, opt.Default); err != nil {
+			return fmt.Errorf("option '%s' default value: %w", opt.LongName, err)
+		}
+		opt.Source = SourceDefault
+	} else {
+		return nil
+	}
+	if err = checkChoices(opt); err != nil {
+		return
+	}
+	if err = validateValues(opt, opt.Values); err != nil {
+		return
+	}
+	if err = self.setVar(opt, 0); err != nil {
+		return fmt.Errorf("invalid Var '%v' for option '%s': %w", opt.Var, opt.LongName, err)
+	}
+	return nil
+}
+
+// fallbackValues returns Values for option from a value given outside of
+// arguments, validated according to option Kind.
+func fallbackValues(option *Option, value string) (Values, error) {
+	switch option.Kind {
+	case Boolean:
+		var b, err = strconv.ParseBool(value)
+		if err != nil {
+			return nil, errors.New("requires a boolean value")
+		}
+		return Values{strconv.FormatBool(b)}, nil
+	case Counted:
+		if count, err := strconv.Atoi(value); err != nil || count < 0 {
+			return nil, errors.New("requires a non-negative count")
+		}
+	case KeyValue:
+		if _, _, err := splitKeyValue(option, value); err != nil {
+			return nil, err
+		}
+	}
+	if option.Arity > 1 {
+		var values = splitQuoted(value, aritySeparator(option))
+		if len(values) != option.Arity {
+			return nil, fmt.Errorf("expects %d values, got %d", option.Arity, len(values))
+		}
+		return values, nil
+	}
+	return splitValues(option, value, value), nil
+}
+
+// argumentValues returns values given to a valued option by the current
+// argument in config where raw is the argument value as given and value is
+// raw with quotes removed.
+//
+// If option has an [Option.Arity] greater than one, in assignment mode raw
+// is split into Arity values, otherwise Arity-1 following arguments are
+// consumed from config. An error is returned if there are not exactly Arity
+// values.
+func argumentValues(config *Config, option *Option, raw, value string) (Values, error) {
+	if option.Arity < 2 {
+		return splitValues(option, raw, value), nil
+	}
+	if config.UseAssignment {
+		var values = splitQuoted(raw, aritySeparator(option))
+		if len(values) != option.Arity {
+			return nil, config.argumentError(ErrMissingValue, config.argIndex(), option,
+				fmt.Errorf("option '%s' expects %d values, got %d", option.LongName, option.Arity, len(values)))
+		}
+		return values, nil
+	}
+	var (
+		index  = config.argIndex() - 1
+		values = Values{value}
+	)
+	for len(values) < option.Arity {
+		if config.Args[1:].Kind(config) != TextArgument {
+			return nil, config.argumentError(ErrMissingValue, index, option,
+				fmt.Errorf("option '%s' expects %d values, got %d", option.LongName, option.Arity, len(values)))
+		}
+		config.Args.Next()
+		values = append(values, config.Args.First())
+	}
+	return values, nil
+}
+
+// aritySeparator returns the separator of values of an option with an
+// [Option.Arity] greater than one given in a single argument.
+func aritySeparator(option *Option) string {
+	if option.Separator == "" {
+		return DefaultAritySeparator
+	}
+	return option.Separator
+}
+
+// splitValues splits raw value of a [Repeated] or a [Variadic] option on its
+// [Option.Separator] and returns the segments. If option has no Separator it
+// returns value which is raw with quotes removed, if any.
+func splitValues(option *Option, raw, value string) Values {
+	if option.Separator == "" {
+		return Values{value}
+	}
+	return splitQuoted(raw, option.Separator)
+}
+
+// splitQuoted splits raw on separator outside of double quoted segments and
+// returns the segments with quotes removed.
+func splitQuoted(raw, separator string) (out Values) {
+	var (
+		start  int
+		quoted bool
+	)
+	for i := 0; i < len(raw); i++ {
+		switch {
+		case raw[i] == '\\' && quoted:
+			i++
+		case raw[i] == '"':
+			quoted = !quoted
+		case !quoted && strings.HasPrefix(raw[i:], separator):
+			out = append(out, unquoteSegment(raw[start:i]))
+			i += len(separator) - 1
+			start = i + 1
+		}
+	}
+	return append(out, unquoteSegment(raw[start:]))
+}
+
+// unquoteSegment returns a segment of a separated value trimmed of spaces
+// and with double quotes removed.
+func unquoteSegment(segment string) string {
+	segment, _ = strutils.UnquoteDouble(strings.TrimSpace(segment))
+	return segment
+}
+
+// keySeparator returns the key separator of a [KeyValue] option.
+func keySeparator(option *Option) string {
+	if option.KeySeparator == "" {
+		return DefaultKeySeparator
+	}
+	return option.KeySeparator
+}
+
+// splitKeyValue splits value of a [KeyValue] option into a key and a value
+// or returns an error if value is not in key-value format.
+func splitKeyValue(option *Option, value string) (key, val string, err error) {
+	var separator = keySeparator(option)
+	var found bool
+	if key, val, found = strings.Cut(value, separator); !found || key == "" {
+		return "", "", fmt.Errorf("option '%s' requires a value in <key>%s<value> format", option.LongName, separator)
+	}
 	return
 }
 
+// addKeyValue appends value to Values of a [KeyValue] option according to
+// its [Option.DuplicateKeys] or returns an error.
+func addKeyValue(option *Option, value string) error {
+	var key, _, err = splitKeyValue(option, value)
+	if err != nil {
+		return err
+	}
+	for _, existing := range option.Values {
+		if k, _, _ := splitKeyValue(option, existing); k != key {
+			continue
+		}
+		switch option.DuplicateKeys {
+		case FirstKeyWins:
+			return nil
+		case RejectDuplicateKeys:
+			return fmt.Errorf("option '%s' key '%s' specified multiple times", option.LongName, key)
+		}
+	}
+	option.Values = append(option.Values, value)
+	return nil
+}
+
+// checkChoices returns an error if option has [Option.Choices] and any of its
+// values is not one of them.
+func checkChoices(option *Option) error {
+	if len(option.Choices) == 0 || option.Kind == Boolean || option.Kind == Counted || option.Kind == KeyValue {
+		return nil
+	}
+	for _, value := range option.Values {
+		if !slices.Contains(option.Choices, value) {
+			return fmt.Errorf("invalid value '%s' for option '%s', valid choices are: %s", value, option.LongName, strings.Join(option.Choices, ", "))
+		}
+	}
+	return nil
+}
+
+// persistentOptions returns Persistent options of commands in chain, nearest
+// command first, followed by Persistent options in config Globals or all
+// named Globals if [Config.GlobalsAnywhere] is enabled.
+func persistentOptions(config *Config, chain []*Command) (out Options) {
+	for i := len(chain) - 1; i >= 0; i-- {
+		for _, option := range chain[i].Options {
+			if option.Persistent {
+				out = append(out, option)
+			}
+		}
+	}
+	for _, option := range config.Globals {
+		if option.Persistent || (config.GlobalsAnywhere && option.Kind != Indexed && option.Kind != Variadic) {
+			out = append(out, option)
+		}
+	}
+	return
+}
+
+// nextIsValue returns true if the argument following the current argument in
+// config may be a value of an [OptionalValue] option, i.e. it exists and is
+// neither an option, the terminator nor a name of a command that may follow.
+func nextIsValue(config *Config) bool {
+	var next = config.Args[1:]
+	if next.Kind(config) != TextArgument {
+		return false
+	}
+	var commands = config.Commands
+	if n := len(config.chain); n > 0 {
+		commands = config.chain[n-1].SubCommands
+	}
+	if cmd, err := commands.find(config, next.First()); cmd != nil || err != nil {
+		return false
+	}
+	return true
+}
+
+// lookupLong returns an Option by long name key from self or, if not found,
+// from Persistent options inherited from the parsed command chain.
+// An exact match in any of them takes precedence over an abbreviation.
+// negated is true if key is a negated name of a Negatable Boolean option.
+// Returns a nil option if not found or an error if key is ambiguous.
+func (self Options) lookupLong(config *Config, key string) (opt *Option, negated bool, err error) {
+	var sets = []Options{self, persistentOptions(config, config.chain)}
+	for _, options := range sets {
+		if opt = options.FindLong(key); opt != nil {
+			return opt, false, nil
+		}
+		if name, ok := strings.CutPrefix(key, NegationPrefix); ok {
+			if opt = options.FindLong(name); opt != nil && opt.Kind == Boolean && opt.Negatable {
+				return opt, true, nil
+			}
+		}
+	}
+	if !config.Abbreviate {
+		return nil, false, nil
+	}
+	for _, options := range sets {
+		if opt, err = options.findLong(config, key); err != nil || opt != nil {
+			return
+		}
+		if opt, err = options.findNegated(config, key); err != nil || opt != nil {
+			return opt, opt != nil, err
+		}
+	}
+	return
+}
+
+// lookupShort returns an Option by short name key from self or, if not found,
+// from Persistent options inherited from the parsed command chain.
+// Returns nil if not found.
+func (self Options) lookupShort(config *Config, key string) *Option {
+	if opt := self.FindShort(key); opt != nil {
+		return opt
+	}
+	return persistentOptions(config, config.chain).FindShort(key)
+}
+
 // getFirstUnparsedIndexed returns the first Indexed Option that is not parsed.
 // Returns nil if none found.
 func (self Options) getFirstUnparsedIndexed() *Option {
@@ -360,24 +861,81 @@ func (self Options) getFirstUnparsedIndexed() *Option {
 	return nil
 }
 
-// setVar converts option.State.RawValue to option.MappedValue if option's
-// raw value is not nil.
-//
-// Returns nil on success of no value mapped.. Returns a non nil error on
-// failed conversion only.
+// find returns a Command from self by name or nil if not found. If
+// [Config.Abbreviate] is enabled name may be an unambiguous prefix of a
+// Command name. Returns an error if name is ambiguous.
+func (self Commands) find(config *Config, name string) (*Command, error) {
+	if !config.Abbreviate {
+		return self.Find(name), nil
+	}
+	switch matches := self.Match(name); len(matches) {
+	case 0:
+		return nil, nil
+	case 1:
+		return matches[0], nil
+	default:
+		var names []string
+		for _, match := range matches {
+			names = append(names, match.Name)
+		}
+		return nil, fmt.Errorf("%s is ambiguous: %s", name, strings.Join(names, ", "))
+	}
+}
+
+// findLong returns an Option from self by long name key or nil if not found.
+// If [Config.Abbreviate] is enabled key may be an unambiguous prefix of a
+// named Option long name. Returns an error if key is ambiguous.
+func (self Options) findLong(config *Config, key string) (*Option, error) {
+	if !config.Abbreviate {
+		return self.FindLong(key), nil
+	}
+	var matches Options
+	for _, option := range self.MatchLong(key) {
+		if option.LongName == key || (option.Kind != Indexed && option.Kind != Variadic) {
+			matches = append(matches, option)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return nil, nil
+	case 1:
+		return matches[0], nil
+	default:
+		var names []string
+		for _, match := range matches {
+			names = append(names, config.LongPrefix+match.LongName)
+		}
+		return nil, fmt.Errorf("%s%s is ambiguous: %s", config.LongPrefix, key, strings.Join(names, ", "))
+	}
+}
+
+// findNegated returns a Negatable Boolean Option whose long name prefixed
+// with [NegationPrefix] equals key. Returns nil if none found.
+func (self Options) findNegated(config *Config, key string) (*Option, error) {
+	if name, ok := strings.CutPrefix(key, NegationPrefix); ok {
+		if opt, err := self.findLong(config, name); err != nil || opt == nil {
+			return nil, err
+		} else if opt.Kind == Boolean && opt.Negatable {
+			return opt, nil
+		}
+	}
+	return nil, nil
+}
+
+// setVar converts option Values to option Var if option has a Var and
+// Values. Supported Var types are described in [Option.Var].
 //
-// option.MappedValue must be a pointer to a supported type or any type that
-// supports conversion from a string by implementing the Value interface.
+// Returns nil on success or if no value was converted. Returns a non nil
+// error on failed conversion only.
 //
-// Supported types are:
-// *bool, *string, *float32, *float64,
-// *int, *int8, *int16, *1nt32, *int64,
-// *uint, *uint8, *uint16, *u1nt32, *uint64
-// *time.Duration, *[]string, and any type supporting Value interface.
+// from is the index of the first value in option Values given by the last
+// argument. Repeated options convert only values from that index on. A
+// conversion error of a single value is returned as a *[ValueError] whose
+// Index is the index of the value in option Values.
 //
-// If an unsupported type was set as option.MappedValue Parse will return a
+// If an unsupported type was set as option Var Parse will return a
 // conversion error.
-func (self Options) setVar(option *Option) (err error) {
+func (self Options) setVar(option *Option, from int) (err error) {
 
 	if option.Var == nil {
 		return nil
@@ -390,10 +948,16 @@ func (self Options) setVar(option *Option) (err error) {
 	}
 
 	switch option.Kind {
-	case Boolean, Optional, Required, Indexed, Variadic:
-		return convertToVar(option.Var, option.Values)
+	case Boolean, Optional, Required, Indexed, Variadic, Counted, OptionalValue:
+		return convertToVar(option.Var, option.Values, option.Layout)
 	case Repeated:
-		return convertToVar(option.Var, option.Values[len(option.Values)-1:])
+		var ve *ValueError
+		if err = convertToVar(option.Var, option.Values[from:], option.Layout); errors.As(err, &ve) {
+			ve.Index += from
+		}
+		return
+	case KeyValue:
+		return convertToMap(option, option.Values)
 	default:
 		return errors.New("invalid OptionKind")
 	}
@@ -401,63 +965,88 @@ func (self Options) setVar(option *Option) (err error) {
 
 // convertToVar sets v which must be a pointer to a supported type from raw
 // or returns an error if conversion error occured.
-func convertToVar(v any, raw Values) (err error) {
+//
+// If v is a pointer to a nil pointer to a supported type a new variable is
+// allocated, set from raw and assigned to it only if conversion succeeded.
+//
+// layout is the layout of time.Time values, time.RFC3339 if empty.
+func convertToVar(v any, raw Values, layout string) (err error) {
 
-	// TODO Accept pointer to a pointer (null-like behaviour).
+	if p, ok := v.(*time.Time); ok && layout != "" {
+		*p, err = time.Parse(layout, raw.First())
+		return
+	}
 
 	if tu, ok := v.(encoding.TextUnmarshaler); ok {
 		return tu.UnmarshalText([]byte(raw.First()))
 	}
 
+	if p := reflect.ValueOf(v); p.Kind() == reflect.Pointer && !p.IsNil() && p.Elem().Kind() == reflect.Pointer {
+		if _, ok := v.(Value); !ok {
+			if !p.Elem().IsNil() {
+				return convertToVar(p.Elem().Interface(), raw, layout)
+			}
+			var target = reflect.New(p.Elem().Type().Elem())
+			if err = convertToVar(target.Interface(), raw, layout); err == nil {
+				p.Elem().Set(target)
+			}
+			return
+		}
+	}
+
 	switch p := v.(type) {
 	case *bool:
-		*p = true
+		if raw.IsEmpty() {
+			*p = true
+		} else {
+			*p, err = strconv.ParseBool(raw.First())
+		}
 	case *string:
 		*p = raw.First()
 	case *int:
 		var v int64
-		if v, err = strconv.ParseInt(raw.First(), 10, 0); err == nil {
+		if v, err = strconv.ParseInt(raw.First(), intBase(raw.First()), 0); err == nil {
 			*p = int(v)
 		}
 	case *uint:
 		var v uint64
-		if v, err = strconv.ParseUint(raw.First(), 10, 0); err == nil {
+		if v, err = strconv.ParseUint(raw.First(), intBase(raw.First()), 0); err == nil {
 			*p = uint(v)
 		}
 	case *int8:
 		var v int64
-		if v, err = strconv.ParseInt(raw.First(), 10, 8); err == nil {
+		if v, err = strconv.ParseInt(raw.First(), intBase(raw.First()), 8); err == nil {
 			*p = int8(v)
 		}
 	case *uint8:
 		var v uint64
-		if v, err = strconv.ParseUint(raw.First(), 10, 8); err == nil {
+		if v, err = strconv.ParseUint(raw.First(), intBase(raw.First()), 8); err == nil {
 			*p = uint8(v)
 		}
 	case *int16:
 		var v int64
-		if v, err = strconv.ParseInt(raw.First(), 10, 16); err == nil {
+		if v, err = strconv.ParseInt(raw.First(), intBase(raw.First()), 16); err == nil {
 			*p = int16(v)
 		}
 	case *uint16:
 		var v uint64
-		if v, err = strconv.ParseUint(raw.First(), 10, 16); err == nil {
+		if v, err = strconv.ParseUint(raw.First(), intBase(raw.First()), 16); err == nil {
 			*p = uint16(v)
 		}
 	case *int32:
 		var v int64
-		if v, err = strconv.ParseInt(raw.First(), 10, 32); err == nil {
+		if v, err = strconv.ParseInt(raw.First(), intBase(raw.First()), 32); err == nil {
 			*p = int32(v)
 		}
 	case *uint32:
 		var v uint64
-		if v, err = strconv.ParseUint(raw.First(), 10, 32); err == nil {
+		if v, err = strconv.ParseUint(raw.First(), intBase(raw.First()), 32); err == nil {
 			*p = uint32(v)
 		}
 	case *int64:
-		*p, err = strconv.ParseInt(raw.First(), 10, 64)
+		*p, err = strconv.ParseInt(raw.First(), intBase(raw.First()), 64)
 	case *uint64:
-		*p, err = strconv.ParseUint(raw.First(), 10, 64)
+		*p, err = strconv.ParseUint(raw.First(), intBase(raw.First()), 64)
 	case *float32:
 		var v float64
 		if v, err = strconv.ParseFloat(raw.First(), 64); err == nil {
@@ -471,12 +1060,196 @@ func convertToVar(v any, raw Values) (err error) {
 		*p, err = time.ParseDuration(raw.First())
 	case *time.Time:
 		*p, err = time.Parse(time.RFC3339, raw.First())
+	case *os.FileMode:
+		var v uint64
+		if v, err = strconv.ParseUint(strings.TrimPrefix(raw.First(), "0o"), 8, 32); err != nil {
+			return fmt.Errorf("invalid file mode '%s', expected an octal number", raw.First())
+		}
+		*p = os.FileMode(v)
+	case *net.IPNet:
+		var n *net.IPNet
+		if _, n, err = net.ParseCIDR(raw.First()); err == nil {
+			*p = *n
+		}
+	case *url.URL:
+		var u *url.URL
+		if u, err = url.Parse(raw.First()); err == nil {
+			*p = *u
+		}
+	case *regexp.Regexp:
+		var r *regexp.Regexp
+		if r, err = regexp.Compile(raw.First()); err == nil {
+			*p = *r
+		}
 	default:
 		if v, ok := p.(Value); ok {
 			err = v.Set(raw)
 		} else {
-			return errors.New("expected pointer to supported type")
+			return convertReflect(p, raw, layout)
 		}
 	}
 	return
 }
+
+// convertReflect sets v which must be a pointer to a type whose underlying
+// type is a basic type, a slice, an array or a struct from raw.
+//
+// Slices, arrays and structs are set by converting each value to the element
+// or exported field of the same index using convertToVar. Slice elements are
+// appended while arrays and structs must have as many elements or exported
+// fields as there are values.
+func convertReflect(v any, raw Values, layout string) (err error) {
+	var p = reflect.ValueOf(v)
+	if p.Kind() != reflect.Pointer || p.IsNil() {
+		return errors.New("expected pointer to supported type")
+	}
+	var (
+		elem    = p.Elem()
+		targets []reflect.Value
+	)
+	switch elem.Kind() {
+	case reflect.Bool:
+		var b bool
+		if b, err = strconv.ParseBool(raw.First()); err == nil {
+			elem.SetBool(b)
+		}
+		return
+	case reflect.String:
+		elem.SetString(raw.First())
+		return nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		var i int64
+		if i, err = strconv.ParseInt(raw.First(), intBase(raw.First()), elem.Type().Bits()); err == nil {
+			elem.SetInt(i)
+		}
+		return
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		var u uint64
+		if u, err = strconv.ParseUint(raw.First(), intBase(raw.First()), elem.Type().Bits()); err == nil {
+			elem.SetUint(u)
+		}
+		return
+	case reflect.Float32, reflect.Float64:
+		var f float64
+		if f, err = strconv.ParseFloat(raw.First(), elem.Type().Bits()); err == nil {
+			elem.SetFloat(f)
+		}
+		return
+	case reflect.Slice:
+		var out = elem
+		for i, value := range raw {
+			var target = reflect.New(elem.Type().Elem())
+			if err = convertToVar(target.Interface(), Values{value}, layout); err != nil {
+				return &ValueError{i, value, err}
+			}
+			out = reflect.Append(out, target.Elem())
+		}
+		elem.Set(out)
+		return nil
+	case reflect.Array:
+		for i := 0; i < elem.Len(); i++ {
+			targets = append(targets, elem.Index(i))
+		}
+	case reflect.Struct:
+		for i := 0; i < elem.NumField(); i++ {
+			if elem.Type().Field(i).IsExported() {
+				targets = append(targets, elem.Field(i))
+			}
+		}
+	default:
+		return errors.New("expected pointer to supported type")
+	}
+	if len(targets) != len(raw) {
+		return fmt.Errorf("expects %d values, got %d", len(targets), len(raw))
+	}
+	for i, target := range targets {
+		if err = convertToVar(target.Addr().Interface(), raw[i:i+1], layout); err != nil {
+			return &ValueError{i, raw[i], err}
+		}
+	}
+	return nil
+}
+
+// supportedType returns true if a pointer to a variable of type t is
+// supported by convertToVar.
+func supportedType(t reflect.Type) bool {
+	var p = reflect.PointerTo(t)
+	if p.Implements(textUnmarshalerType) || p.Implements(valueType) || slices.Contains(structTypes, t) {
+		return true
+	}
+	switch t.Kind() {
+	case reflect.Bool, reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	case reflect.Slice, reflect.Array, reflect.Pointer:
+		return supportedType(t.Elem())
+	case reflect.Struct:
+		var fields int
+		for i := 0; i < t.NumField(); i++ {
+			if !t.Field(i).IsExported() {
+				continue
+			}
+			if !supportedType(t.Field(i).Type) {
+				return false
+			}
+			fields++
+		}
+		return fields > 0
+	}
+	return false
+}
+
+var (
+	// textUnmarshalerType is the type of encoding.TextUnmarshaler.
+	textUnmarshalerType = reflect.TypeFor[encoding.TextUnmarshaler]()
+	// valueType is the type of Value.
+	valueType = reflect.TypeFor[Value]()
+	// structTypes are struct types explicitly supported by convertToVar.
+	structTypes = []reflect.Type{
+		reflect.TypeFor[net.IPNet](),
+		reflect.TypeFor[url.URL](),
+		reflect.TypeFor[regexp.Regexp](),
+	}
+)
+
+// intBase returns the base to parse integer s with: 0 if s has a '0x', '0o'
+// or '0b' prefix, optionally signed, so that the base is implied by the
+// prefix, or 10 otherwise. A leading zero alone does not imply octal.
+func intBase(s string) int {
+	s = strings.TrimLeft(s, "+-")
+	if len(s) > 2 && s[0] == '0' && strings.ContainsRune("xXoObB", rune(s[1])) {
+		return 0
+	}
+	return 10
+}
+
+// convertToMap sets values of a [KeyValue] option to its Var which must be a
+// pointer to a map or a [Value]. Keys and values of the map are converted
+// using convertToVar. A nil map is allocated.
+func convertToMap(option *Option, raw Values) (err error) {
+	if v, ok := option.Var.(Value); ok {
+		return v.Set(raw)
+	}
+	var p = reflect.ValueOf(option.Var)
+	if p.Kind() != reflect.Pointer || p.Elem().Kind() != reflect.Map {
+		return errors.New("expected pointer to a map")
+	}
+	var m = p.Elem()
+	if m.IsNil() {
+		m.Set(reflect.MakeMap(m.Type()))
+	}
+	for _, value := range raw {
+		var k, v, _ = splitKeyValue(option, value)
+		var key, elem = reflect.New(m.Type().Key()), reflect.New(m.Type().Elem())
+		if err = convertToVar(key.Interface(), Values{k}, option.Layout); err != nil {
+			return fmt.Errorf("key '%s': %w", k, err)
+		}
+		if err = convertToVar(elem.Interface(), Values{v}, option.Layout); err != nil {
+			return fmt.Errorf("key '%s' value '%s': %w", k, v, err)
+		}
+		m.SetMapIndex(key.Elem(), elem.Elem())
+	}
+	return nil
+}
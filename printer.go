@@ -5,6 +5,7 @@
 package cmdline
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"strings"
@@ -19,17 +20,54 @@ func PrintConfig(w io.Writer, config *Config) {
 		PrintOptions(wr, config, config.Globals, 1)
 		io.WriteString(w, "\n")
 	}
+	if len(config.GlobalConstraints) > 0 {
+		io.WriteString(wr, "Global constraints:\n\n")
+		PrintConstraints(wr, config, config.GlobalConstraints, 1)
+		io.WriteString(wr, "\n")
+	}
 	if config.Commands.Count() > 0 {
 		io.WriteString(wr, "Commands:\n\n")
 		PrintCommands(wr, config, config.Commands, 1)
 	}
 }
 
+// PrintError prints err to w. If err is a [ParseError] that refers to an
+// argument, the arguments are printed below the error with a caret under the
+// offending argument. Joined errors, as returned by Parse if
+// [Config.CollectErrors] is enabled, are printed one by one.
+func PrintError(w io.Writer, err error) {
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		if _, ok = err.(*ParseError); !ok {
+			for _, err = range joined.Unwrap() {
+				PrintError(w, err)
+			}
+			return
+		}
+	}
+	fmt.Fprintln(w, err)
+	var pe *ParseError
+	if errors.As(err, &pe) {
+		if caret := pe.Caret(); caret != "" {
+			for _, line := range strings.Split(caret, "\n") {
+				fmt.Fprintf(w, "%s%s\n", indentString(1), line)
+			}
+		}
+	}
+}
+
+// PrintConstraints prints constraints to w idented with ident tabs using
+// config.
+func PrintConstraints(w io.Writer, config *Config, constraints Constraints, indent int) {
+	for _, constraint := range constraints {
+		fmt.Fprintf(w, "%s%s\n", indentString(indent), constraint.describe(config.LongPrefix))
+	}
+}
+
 // PrintCommandsGroup prints only the commands without recursing into subcommands.
 func PrintCommandsGroup(w io.Writer, config *Config, commands Commands, indent int) {
 	var tw = tabwriter.NewWriter(w, 2, 2, 2, 32, 0)
 	for _, command := range commands {
-		fmt.Fprintf(tw, "%s%s\t%s\n", indentString(indent),command.Name,  command.Help)
+		fmt.Fprintf(tw, "%s%s\t%s\n", indentString(indent), commandString(command), command.Help)
 	}
 	tw.Flush()
 }
@@ -51,7 +89,7 @@ func PrintCommandsNoOptions(w io.Writer, config *Config, commands Commands, inde
 // PrintOptions prints commands to w idented with ident tabs using config.
 func printCommandsNoOptions(w io.Writer, config *Config, commands Commands, indent int) {
 	for _, command := range commands {
-		fmt.Fprintf(w, "%s%s\t%s\n", indentString(indent),command.Name,  command.Help)
+		fmt.Fprintf(w, "%s%s\t%s\n", indentString(indent), commandString(command), command.Help)
 		if command.SubCommands.Count() > 0 {
 			printCommandsNoOptions(w, config, command.SubCommands, indent+1)
 		}
@@ -61,7 +99,7 @@ func printCommandsNoOptions(w io.Writer, config *Config, commands Commands, inde
 // PrintCommand prints command to w idented with ident tabs using config.
 func PrintCommand(w io.Writer, config *Config, command *Command, indent int) {
 	io.WriteString(w, indentString(indent))
-	io.WriteString(w, fmt.Sprintf("%s\t%s\n", command.Name, command.Help))
+	io.WriteString(w, fmt.Sprintf("%s\t%s\n", commandString(command), command.Help))
 	if command.Options.Count() > 0 {
 		PrintOptions(w, config, command.Options, indent+1)
 	}
@@ -95,12 +133,24 @@ func PrintOptions(w io.Writer, config *Config, options Options, indent int) {
 		}
 		PrintOption(wr, config, option, indent)
 	}
+	for _, option := range options {
+		if option.Kind != Counted {
+			continue
+		}
+		PrintOption(wr, config, option, indent)
+	}
 	for _, option := range options {
 		if option.Kind != Optional {
 			continue
 		}
 		PrintOption(wr, config, option, indent)
 	}
+	for _, option := range options {
+		if option.Kind != OptionalValue {
+			continue
+		}
+		PrintOption(wr, config, option, indent)
+	}
 	for _, option := range options {
 		if option.Kind != Required {
 			continue
@@ -113,6 +163,12 @@ func PrintOptions(w io.Writer, config *Config, options Options, indent int) {
 		}
 		PrintOption(wr, config, option, indent)
 	}
+	for _, option := range options {
+		if option.Kind != KeyValue {
+			continue
+		}
+		PrintOption(wr, config, option, indent)
+	}
 	for _, option := range options {
 		if option.Kind != Variadic {
 			continue
@@ -127,37 +183,97 @@ func PrintOption(w io.Writer, config *Config, option *Option, indent int) {
 	io.WriteString(w, indentString(indent))
 	switch option.Kind {
 	case Boolean:
-		io.WriteString(w, fmt.Sprintf("%s\t%s\n", optionString(config, option.LongName, option.ShortName, false), option.Help))
+		io.WriteString(w, fmt.Sprintf("%s\t%s\n", optionString(config, option, false), optionHelp(option)))
+	case Counted:
+		var name = optionString(config, option, false)
+		if config.UseAssignment {
+			name += "[=<count>]"
+		} else {
+			name += "..."
+		}
+		io.WriteString(w, fmt.Sprintf("%s\t%s\n", name, optionHelp(option)))
 	case Optional:
-		io.WriteString(w, fmt.Sprintf("%s\t%s\n", optionString(config, option.LongName, option.ShortName, true), option.Help))
+		io.WriteString(w, fmt.Sprintf("%s\t%s\n", optionString(config, option, true), optionHelp(option)))
+	case OptionalValue:
+		io.WriteString(w, fmt.Sprintf("%s[=<value>]\t%s\n", optionString(config, option, false), optionHelp(option)))
 	case Required:
-		io.WriteString(w, fmt.Sprintf("%s\t%s\n", optionString(config, option.LongName, option.ShortName, true), option.Help))
+		io.WriteString(w, fmt.Sprintf("%s\t%s\n", optionString(config, option, true), optionHelp(option)))
 	case Repeated:
-		io.WriteString(w, fmt.Sprintf("%s\t%s\n", optionString(config, option.LongName, option.ShortName, true), option.Help))
+		io.WriteString(w, fmt.Sprintf("%s\t%s\n", optionString(config, option, true), optionHelp(option)))
+	case KeyValue:
+		io.WriteString(w, fmt.Sprintf("%s\t%s\n", optionString(config, option, true), optionHelp(option)))
 	case Indexed:
-		io.WriteString(w, fmt.Sprintf("\t<%s>\t%s\n", option.LongName, option.Help))
+		io.WriteString(w, fmt.Sprintf("\t<%s>\t%s\n", option.LongName, optionHelp(option)))
 	case Variadic:
-		io.WriteString(w, fmt.Sprintf("... \t%s\t%s\n", option.LongName, option.Help))
+		io.WriteString(w, fmt.Sprintf("... \t%s\t%s\n", option.LongName, optionHelp(option)))
 	}
 }
 
+// commandString returns the command name followed by its aliases, if any, for
+// pretty printing.
+func commandString(command *Command) string {
+	return strings.Join(command.Names(), ", ")
+}
+
 // optionString returns the option string representation for pretty printing.
-func optionString(config *Config, longname, shortname string, value bool) (result string) {
-	if shortname != "" {
-		result = fmt.Sprintf("%s%s\t%s%s", config.ShortPrefix, shortname, config.LongPrefix, longname)
-	} else {
-		result = fmt.Sprintf("\t%s%s", config.LongPrefix, longname)
+//
+// Short names and long names including aliases are printed in separate
+// columns. Negatable options are printed with a negation prefix in brackets.
+func optionString(config *Config, option *Option, value bool) (result string) {
+	var shorts, longs []string
+	for _, name := range option.ShortNames() {
+		shorts = append(shorts, config.ShortPrefix+name)
+	}
+	for _, name := range option.LongNames() {
+		if option.Kind == Boolean && option.Negatable {
+			name = "[" + NegationPrefix + "]" + name
+		}
+		longs = append(longs, config.LongPrefix+name)
 	}
+	result = strings.Join(shorts, ", ") + "\t" + strings.Join(longs, ", ")
 	if value {
+		var placeholder = "<value>"
+		if option.Kind == KeyValue {
+			placeholder = "<key>" + keySeparator(option) + "<value>"
+		}
+		if option.Arity > 1 {
+			var separator = " "
+			if config.UseAssignment {
+				separator = aritySeparator(option)
+			}
+			placeholder = strings.Repeat(placeholder+separator, option.Arity-1) + placeholder
+		}
 		if config.UseAssignment {
-			result = result + "=<value>"
+			result = result + "=" + placeholder
 		} else {
-			result = result + " <value>"
+			result = result + " " + placeholder
 		}
 	}
 	return
 }
 
+// optionHelp returns the option help text followed by annotations describing
+// the option, such as the environment variable it is read from.
+func optionHelp(option *Option) (result string) {
+	result = option.Help
+	if len(option.Choices) > 0 {
+		result += " (choices: " + strings.Join(option.Choices, ", ") + ")"
+	}
+	for _, validator := range option.Validators {
+		result += " (" + validator.String() + ")"
+	}
+	if option.ImplicitValue != "" {
+		result += " (implicit: " + option.ImplicitValue + ")"
+	}
+	if option.Default != "" {
+		result += " (default: " + option.Default + ")"
+	}
+	if option.EnvVar != "" {
+		result += " [env: " + option.EnvVar + "]"
+	}
+	return strings.TrimSpace(result)
+}
+
 // indentString returns string of depth times two spaces used for indentation.
 func indentString(depth int) (result string) { return strings.Repeat("  ", depth) }
 
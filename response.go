@@ -0,0 +1,198 @@
+// Copyright 2023-2024 Vedran Vuk. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package cmdline
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+const (
+	// ResponseFilePrefix is the prefix of an argument that names a response
+	// file whose contents are expanded into arguments, e.g. '@args.txt'.
+	ResponseFilePrefix = "@"
+
+	// MaxResponseFileDepth is the maximum nesting depth of response files.
+	MaxResponseFileDepth = 16
+)
+
+// ResponseFileMode specifies if and how response files are read.
+type ResponseFileMode int
+
+const (
+	// NoResponseFiles disables response file expansion.
+	NoResponseFiles ResponseFileMode = iota
+
+	// ResponseFileLines reads each non-blank line of a response file as a
+	// single argument, verbatim.
+	ResponseFileLines
+
+	// ResponseFileTokens reads a response file as shell quoted tokens
+	// separated by whitespace. Tokens may be quoted using single or double
+	// quotes and characters may be escaped with a backslash. A '#' at the
+	// start of a token starts a comment that runs to the end of the line.
+	ResponseFileTokens
+)
+
+// responseToken is an argument read from a response file.
+type responseToken struct {
+	// text is the argument text.
+	text string
+	// line is the line number where the argument starts.
+	line int
+}
+
+// expandResponseFiles replaces arguments in [Config.Args] prefixed with
+// [ResponseFilePrefix] with arguments read from named files, recursively.
+//
+// Arguments following a [Config.Terminator] are not expanded. Relative paths
+// are resolved against the current working directory.
+func (self *Config) expandResponseFiles() (err error) {
+	var (
+		args       Args
+		origins    []string
+		stack      []string
+		terminated bool
+		expand     func(arg, origin string) error
+	)
+	expand = func(arg, origin string) (err error) {
+		var name, ok = strings.CutPrefix(arg, ResponseFilePrefix)
+		if terminated || !ok || name == "" {
+			terminated = terminated || arg == self.Terminator
+			args = append(args, arg)
+			origins = append(origins, origin)
+			return nil
+		}
+		if len(stack) >= MaxResponseFileDepth {
+			return fmt.Errorf("response file '%s' exceeds maximum nesting depth of %d", name, MaxResponseFileDepth)
+		}
+		var abs string
+		if abs, err = filepath.Abs(name); err != nil {
+			return fmt.Errorf("response file '%s': %w", name, err)
+		}
+		if slices.Contains(stack, abs) {
+			return fmt.Errorf("response file '%s' includes itself", name)
+		}
+		var data []byte
+		if data, err = os.ReadFile(name); err != nil {
+			return fmt.Errorf("read response file: %w", err)
+		}
+		var tokens []responseToken
+		if self.ResponseFiles == ResponseFileLines {
+			tokens = splitResponseLines(string(data))
+		} else if tokens, err = splitResponseTokens(string(data)); err != nil {
+			return fmt.Errorf("%s:%w", name, err)
+		}
+		stack = append(stack, abs)
+		for _, token := range tokens {
+			if err = expand(token.text, fmt.Sprintf("%s:%d", name, token.line)); err != nil {
+				return fmt.Errorf("%s:%d: %w", name, token.line, err)
+			}
+		}
+		stack = stack[:len(stack)-1]
+		return nil
+	}
+	for _, arg := range self.Args {
+		if err = expand(arg, ""); err != nil {
+			return
+		}
+	}
+	self.Args, self.origins = args, origins
+	return nil
+}
+
+// originError prefixes the message of err with the response file name and
+// line of the argument it refers to if it was read from a response file.
+func (self *Config) originError(err error) error {
+	var pe *ParseError
+	if !errors.As(err, &pe) {
+		return err
+	}
+	if index := pe.Index; index >= 0 && index < len(self.origins) && self.origins[index] != "" {
+		pe.Err = fmt.Errorf("%s: %w", self.origins[index], pe.Err)
+	}
+	return err
+}
+
+// splitResponseLines returns non-blank lines of data as tokens.
+func splitResponseLines(data string) (out []responseToken) {
+	for index, line := range strings.Split(data, "\n") {
+		if line = strings.TrimSuffix(line, "\r"); strings.TrimSpace(line) != "" {
+			out = append(out, responseToken{line, index + 1})
+		}
+	}
+	return
+}
+
+// splitResponseTokens splits data into shell quoted tokens as described in
+// [ResponseFileTokens]. It returns an error prefixed with a line number if a
+// quote is not terminated.
+func splitResponseTokens(data string) (out []responseToken, err error) {
+	var (
+		runes   = []rune(data)
+		token   strings.Builder
+		inToken bool
+		quote   rune
+		line    = 1
+		start   int
+	)
+	var begin = func() {
+		if !inToken {
+			inToken, start = true, line
+		}
+	}
+	for i := 0; i < len(runes); i++ {
+		var r = runes[i]
+		if r == '\n' {
+			line++
+		}
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else if quote == '"' && r == '\\' && i+1 < len(runes) && strings.ContainsRune(`"\\`, runes[i+1]) {
+				i++
+				token.WriteRune(runes[i])
+			} else {
+				token.WriteRune(r)
+			}
+		case unicode.IsSpace(r):
+			if inToken {
+				out = append(out, responseToken{token.String(), start})
+				token.Reset()
+				inToken = false
+			}
+		case r == '#' && !inToken:
+			for i+1 < len(runes) && runes[i+1] != '\n' {
+				i++
+			}
+		case r == '\'' || r == '"':
+			begin()
+			quote = r
+		case r == '\\' && i+1 < len(runes):
+			begin()
+			i++
+			if runes[i] == '\n' {
+				line++
+			}
+			token.WriteRune(runes[i])
+		default:
+			begin()
+			token.WriteRune(r)
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("%d: unterminated quote", start)
+	}
+	if inToken {
+		out = append(out, responseToken{token.String(), start})
+	}
+	return
+}
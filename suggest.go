@@ -0,0 +1,157 @@
+// Copyright 2023-2024 Vedran Vuk. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package cmdline
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// UnknownError is returned by Parse if an argument refers to a [Command] or
+// an [Option] that is not registered at the current level.
+//
+// It carries the closest registered names as suggestions which can be
+// inspected using errors.As to render them differently.
+type UnknownError struct {
+	// Name is the unknown name as given in arguments, without prefixes.
+	Name string
+	// Command is true if Name was given in place of a Command name, false if
+	// in place of an Option name.
+	Command bool
+	// Suggestions are the registered names closest to Name, closest first.
+	// It may be empty if no name was close enough.
+	Suggestions []string
+}
+
+// Error implements the error interface.
+func (self *UnknownError) Error() (s string) {
+	if self.Command {
+		s = fmt.Sprintf("command '%s' not registered", self.Name)
+	} else {
+		s = fmt.Sprintf("unknown option '%s'", self.Name)
+	}
+	if len(self.Suggestions) == 0 {
+		return
+	}
+	var quoted = make([]string, 0, len(self.Suggestions))
+	for _, suggestion := range self.Suggestions {
+		quoted = append(quoted, "'"+suggestion+"'")
+	}
+	if last := len(quoted) - 1; last > 0 {
+		return fmt.Sprintf("%s, did you mean %s or %s?", s, strings.Join(quoted[:last], ", "), quoted[last])
+	}
+	return fmt.Sprintf("%s, did you mean %s?", s, quoted[0])
+}
+
+// unknownCommand returns an [UnknownError] for an unknown command name with
+// suggestions from commands.
+func unknownCommand(commands Commands, name string) error {
+	var names []string
+	for _, command := range commands {
+		names = append(names, command.Names()...)
+	}
+	return &UnknownError{
+		Name:        name,
+		Command:     true,
+		Suggestions: suggest(name, names),
+	}
+}
+
+// unknownOption returns an [UnknownError] for an unknown option name with
+// suggestions from long or short names of named options in options. As any
+// single letter is close to another, a single letter short name is suggested
+// only short names that differ from it in case.
+func unknownOption(options Options, name string, long bool) error {
+	var names []string
+	for _, option := range options {
+		if option.Kind == Indexed || option.Kind == Variadic {
+			continue
+		}
+		if long {
+			names = append(names, option.LongNames()...)
+		} else {
+			names = append(names, option.ShortNames()...)
+		}
+	}
+	if !long && utf8.RuneCountInString(name) == 1 {
+		names = slices.DeleteFunc(names, func(short string) bool {
+			return !strings.EqualFold(short, name)
+		})
+	}
+	return &UnknownError{
+		Name:        name,
+		Suggestions: suggest(name, names),
+	}
+}
+
+// maxSuggestions is the maximum number of suggestions returned by suggest.
+const maxSuggestions = 3
+
+// suggest returns names from candidates that are close to name, closest
+// first. A candidate is close if name is its prefix or if their edit
+// distance is within a third of the length of name, rounded up. Duplicate
+// candidates are suggested once.
+func suggest(name string, candidates []string) (out []string) {
+	type match struct {
+		name     string
+		distance int
+	}
+	var (
+		matches []match
+		limit   = max(1, (len(name)+2)/3)
+	)
+	for i, candidate := range candidates {
+		if slices.Contains(candidates[:i], candidate) {
+			continue
+		}
+		var distance = editDistance(name, candidate)
+		if name != "" && strings.HasPrefix(candidate, name) {
+			distance = min(distance, 1)
+		}
+		if distance <= limit {
+			matches = append(matches, match{candidate, distance})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].distance < matches[j].distance
+	})
+	for i := 0; i < len(matches) && i < maxSuggestions; i++ {
+		out = append(out, matches[i].name)
+	}
+	return
+}
+
+// editDistance returns the optimal string alignment distance between a and
+// b; the number of insertions, deletions, substitutions and transpositions
+// of adjacent characters required to turn a into b.
+func editDistance(a, b string) int {
+	var (
+		ra, rb = []rune(a), []rune(b)
+		d      = make([][]int, len(ra)+1)
+	)
+	for i := range d {
+		d[i] = make([]int, len(rb)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		for j := 1; j <= len(rb); j++ {
+			var cost = 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+	return d[len(ra)][len(rb)]
+}
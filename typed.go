@@ -0,0 +1,89 @@
+// Copyright 2023-2024 Vedran Vuk. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package cmdline
+
+import "fmt"
+
+// Handle is a typed handle to an [Option] whose Var is a variable of type T
+// owned by the Handle. It is returned by [Register] and [Define].
+type Handle[T any] struct {
+	// Option is the registered Option.
+	Option *Option
+	// value is the Option Var.
+	value *T
+}
+
+// Value returns the value of the Option as converted by Parse, its default
+// value or a zero value of T if the Option was not parsed.
+func (self *Handle[T]) Value() T { return *self.value }
+
+// Var returns a pointer to the variable of the Option.
+func (self *Handle[T]) Var() *T { return self.value }
+
+// Parsed returns true if the Option was parsed. See [Context.Parsed].
+func (self *Handle[T]) Parsed() bool { return self.Option.isSet() }
+
+// Register sets a new variable of type T as Var of option, registers option
+// in options and returns a typed handle to it.
+//
+// Whether T is supported by option Kind is validated by Parse before any
+// arguments are parsed.
+func Register[T any](options *Options, option *Option) *Handle[T] {
+	var out = &Handle[T]{option, new(T)}
+	option.Var = out.value
+	options.Register(option)
+	return out
+}
+
+// Define registers a new Option of kind in options with a variable of type T
+// and returns a typed handle to it. For Indexed and Variadic options
+// shortName must be empty.
+func Define[T any](options *Options, kind Kind, longName, shortName, help string) *Handle[T] {
+	return Register[T](options, &Option{
+		LongName:  longName,
+		ShortName: shortName,
+		Help:      help,
+		Kind:      kind,
+	})
+}
+
+// Get returns the value of the [Option] under longName in c as type T or a
+// zero value of T if the Option was not found or its value could not be
+// converted to T. See [Lookup].
+func Get[T any](c Context, longName string) T {
+	var value, _ = Lookup[T](c, longName)
+	return value
+}
+
+// Lookup returns the value of the [Option] under longName in c as type T or
+// an error if the Option was not found or its value could not be converted
+// to T.
+//
+// If the Option Var is a *T its value, converted once by Parse, is returned.
+// Otherwise Option's Values are converted to T. A zero value of T is
+// returned for an Option that was not parsed and has no default value.
+func Lookup[T any](c Context, longName string) (value T, err error) {
+	var option = c.Option(longName)
+	if option == nil {
+		return value, fmt.Errorf("option '%s' not found", longName)
+	}
+	if p, ok := option.Var.(*T); ok {
+		return *p, nil
+	}
+	if !option.IsParsed && option.Values.Count() == 0 {
+		return value, nil
+	}
+	if option.Kind == KeyValue {
+		var temp = *option
+		temp.Var = &value
+		err = convertToMap(&temp, option.Values)
+	} else {
+		err = convertToVar(&value, option.Values, option.Layout)
+	}
+	if err != nil {
+		return value, fmt.Errorf("option '%s': %w", longName, err)
+	}
+	return
+}
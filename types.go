@@ -0,0 +1,79 @@
+// Copyright 2023-2024 Vedran Vuk. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package cmdline
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// ByteSize is a size in bytes that can be given in human readable form,
+// e.g. '512', '10MiB', '1.5G' or '4kb'.
+//
+// Units are case insensitive. Binary units 'Ki', 'Mi', 'Gi', 'Ti', 'Pi' and
+// 'Ei' are powers of 1024 and decimal units 'K', 'M', 'G', 'T', 'P' and 'E'
+// are powers of 1000. Units may be followed by an optional 'B' and a value
+// with no unit or unit 'B' is in bytes.
+type ByteSize uint64
+
+// byteUnits are multipliers of [ByteSize] units.
+var byteUnits = map[string]float64{
+	"":   1,
+	"k":  1e3,
+	"m":  1e6,
+	"g":  1e9,
+	"t":  1e12,
+	"p":  1e15,
+	"e":  1e18,
+	"ki": 1 << 10,
+	"mi": 1 << 20,
+	"gi": 1 << 30,
+	"ti": 1 << 40,
+	"pi": 1 << 50,
+	"ei": 1 << 60,
+}
+
+// UnmarshalText implements [encoding.TextUnmarshaler].
+func (self *ByteSize) UnmarshalText(text []byte) error {
+	var s = strings.TrimSpace(string(text))
+	var index = strings.IndexFunc(s, func(r rune) bool {
+		return !unicode.IsDigit(r) && r != '.'
+	})
+	if index < 0 {
+		index = len(s)
+	}
+	var number, unit = s[:index], strings.ToLower(strings.TrimSpace(s[index:]))
+	var multiplier, ok = byteUnits[strings.TrimSuffix(unit, "b")]
+	if !ok || number == "" {
+		return fmt.Errorf("invalid byte size '%s'", s)
+	}
+	var value, err = strconv.ParseFloat(number, 64)
+	if err != nil {
+		return fmt.Errorf("invalid byte size '%s'", s)
+	}
+	if value *= multiplier; value >= math.MaxUint64 {
+		return fmt.Errorf("byte size '%s' out of range", s)
+	}
+	*self = ByteSize(value)
+	return nil
+}
+
+// String implements [fmt.Stringer]. It returns the size in the largest
+// binary unit that represents it exactly, e.g. '10MiB'.
+func (self ByteSize) String() string {
+	for _, unit := range []string{"Ei", "Pi", "Ti", "Gi", "Mi", "Ki"} {
+		var multiplier = uint64(byteUnits[strings.ToLower(unit)])
+		if self != 0 && uint64(self)%multiplier == 0 {
+			return strconv.FormatUint(uint64(self)/multiplier, 10) + unit + "B"
+		}
+	}
+	return strconv.FormatUint(uint64(self), 10) + "B"
+}
+
+// MarshalText implements [encoding.TextMarshaler].
+func (self ByteSize) MarshalText() ([]byte, error) { return []byte(self.String()), nil }
@@ -5,8 +5,9 @@
 package cmdline
 
 import (
-	"errors"
 	"fmt"
+	"reflect"
+	"slices"
 )
 
 // ValidateOptions validates that Option instances within options have unique
@@ -16,29 +17,90 @@ func ValidateOptions(options Options) error {
 	var hasVariadic string
 	for _, option := range options {
 		switch option.Kind {
-		case Boolean, Optional, Required, Indexed, Repeated:
+		case Boolean, Optional, Required, Indexed, Repeated, Counted, KeyValue, OptionalValue:
 		case Variadic:
 			if hasVariadic != "" {
-				return fmt.Errorf("validation failed: multiple variadic options in options set: %s and %s", hasVariadic, option.LongName)
+				return validationErrorf(option, "validation failed: multiple variadic options in options set: %s and %s", hasVariadic, option.LongName)
 			}
 			hasVariadic = option.LongName
 		default:
-			return errors.New("validation failed: invalid option type, must be a pointer to one of supported option types")
+			return validationErrorf(option, "validation failed: invalid option type, must be a pointer to one of supported option types")
 		}
 		if option.LongName == "" {
-			return errors.New("validation failed: an option with an empty long name is defined")
+			return validationErrorf(option, "validation failed: an option with an empty long name is defined")
 		}
-		for _, other := range options {
-			if other != option && other.LongName == option.LongName {
-				return fmt.Errorf("validation failed: duplicate option long name: %s", option.LongName)
+		if option.Persistent && (option.Kind == Indexed || option.Kind == Variadic) {
+			return validationErrorf(option, "validation failed: %s option '%s' cannot be persistent", option.Kind, option.LongName)
+		}
+		if option.EnvVar != "" && (option.Kind == Indexed || option.Kind == Variadic) {
+			return validationErrorf(option, "validation failed: %s option '%s' cannot use an environment variable", option.Kind, option.LongName)
+		}
+		if option.Default != "" && (option.Kind == Required || option.Kind == Indexed) {
+			return validationErrorf(option, "validation failed: %s option '%s' cannot have a default value", option.Kind, option.LongName)
+		}
+		if err := checkVar(option); err != nil {
+			return validationErrorf(option, "validation failed: option '%s' %v", option.LongName, err)
+		}
+		if option.Kind == OptionalValue && option.ImplicitValue == "" {
+			return validationErrorf(option, "validation failed: %s option '%s' requires an implicit value", option.Kind, option.LongName)
+		}
+		if option.Arity < 0 {
+			return validationErrorf(option, "validation failed: option '%s' has a negative arity", option.LongName)
+		}
+		if option.Arity > 1 && option.Kind != Optional && option.Kind != Required && option.Kind != Repeated {
+			return validationErrorf(option, "validation failed: %s option '%s' cannot have an arity", option.Kind, option.LongName)
+		}
+		if option.Separator != "" && option.Kind != Repeated && option.Kind != Variadic && option.Arity < 2 {
+			return validationErrorf(option, "validation failed: %s option '%s' cannot have a separator", option.Kind, option.LongName)
+		}
+		if len(option.Validators) > 0 && (option.Kind == Boolean || option.Kind == Counted) {
+			return validationErrorf(option, "validation failed: %s option '%s' cannot have validators", option.Kind, option.LongName)
+		}
+		if slices.Contains(option.Validators, nil) {
+			return validationErrorf(option, "validation failed: option '%s' has a nil validator", option.LongName)
+		}
+		if len(option.Choices) > 0 {
+			if option.Kind == Boolean || option.Kind == Counted || option.Kind == KeyValue {
+				return validationErrorf(option, "validation failed: %s option '%s' cannot have choices", option.Kind, option.LongName)
+			}
+			if option.Default != "" && !slices.Contains(option.Choices, option.Default) {
+				return validationErrorf(option, "validation failed: option '%s' default value '%s' is not one of its choices", option.LongName, option.Default)
+			}
+			if option.ImplicitValue != "" && !slices.Contains(option.Choices, option.ImplicitValue) {
+				return validationErrorf(option, "validation failed: option '%s' implicit value '%s' is not one of its choices", option.LongName, option.ImplicitValue)
+			}
+		}
+		for i, name := range option.LongNames() {
+			if name == "" {
+				return validationErrorf(option, "validation failed: option '%s' has an empty long alias", option.LongName)
+			}
+			if slices.Contains(option.LongNames()[:i], name) {
+				return validationErrorf(option, "validation failed: option '%s' has a duplicate long alias: %s", option.LongName, name)
+			}
+			for _, other := range options {
+				if other != option && other.HasLongName(name) {
+					return validationErrorf(option, "validation failed: duplicate option long name: %s", name)
+				}
+			}
+			if option.Negatable {
+				if option.Kind != Boolean {
+					return validationErrorf(option, "validation failed: option '%s' is negatable but not a boolean", option.LongName)
+				}
+				if options.FindLong(NegationPrefix+name) != nil {
+					return validationErrorf(option, "validation failed: negated option '%s' conflicts with option '%s'", name, NegationPrefix+name)
+				}
 			}
 		}
-		if option.ShortName != "" {
+		for i, name := range option.ShortNames() {
+			if name == "" {
+				return validationErrorf(option, "validation failed: option '%s' has an empty short alias", option.LongName)
+			}
+			if slices.Contains(option.ShortNames()[:i], name) {
+				return validationErrorf(option, "validation failed: option '%s' has a duplicate short alias: %s", option.LongName, name)
+			}
 			for _, other := range options {
-				if other != option && other.ShortName != "" {
-					if other.ShortName == option.ShortName {
-						return fmt.Errorf("validation failed: duplicate option short name: %s", option.LongName)
-					}
+				if other != option && other.HasShortName(name) {
+					return validationErrorf(option, "validation failed: duplicate option short name: %s", option.LongName)
 				}
 			}
 		}
@@ -46,6 +108,31 @@ func ValidateOptions(options Options) error {
 	return nil
 }
 
+// checkVar returns an error if [Option.Var] of option is not nil and is not a
+// pointer to a type supported by option Kind.
+func checkVar(option *Option) error {
+	if option.Var == nil {
+		return nil
+	}
+	if _, ok := option.Var.(Value); ok {
+		return nil
+	}
+	var t = reflect.TypeOf(option.Var)
+	if t.Kind() != reflect.Pointer {
+		return fmt.Errorf("Var of type %T is not a pointer", option.Var)
+	}
+	if option.Kind == KeyValue {
+		if t.Elem().Kind() != reflect.Map || !supportedType(t.Elem().Key()) || !supportedType(t.Elem().Elem()) {
+			return fmt.Errorf("Var of type %T is not a pointer to a map of supported types", option.Var)
+		}
+		return nil
+	}
+	if !supportedType(t.Elem()) {
+		return fmt.Errorf("Var of type %T is not a pointer to a supported type", option.Var)
+	}
+	return nil
+}
+
 // optionsHaveVariadicOption returns true if options contain at least one
 // Variadic Option.
 func optionsHaveVariadicOption(options Options) bool {
@@ -57,60 +144,128 @@ func optionsHaveVariadicOption(options Options) bool {
 	return false
 }
 
+// isGiven returns true if option is not nil and was parsed from arguments or,
+// if arguments is false, read from environment.
+func isGiven(option *Option, arguments bool) bool {
+	return option != nil && option.isSet() && (!arguments || option.Source == SourceArguments)
+}
+
+// givenInArguments returns true if any of the options named by names was
+// parsed from arguments.
+func givenInArguments(options Options, names []string) bool {
+	return slices.ContainsFunc(names, func(name string) bool {
+		return isGiven(options.FindLong(name), true)
+	})
+}
+
+// optionsHavePersistentOption returns true if options contain a Persistent
+// option.
+func optionsHavePersistentOption(options Options) bool {
+	for _, opt := range options {
+		if opt.Persistent {
+			return true
+		}
+	}
+	return false
+}
+
 // ValidateCommands validates that Command instances within commands have
 // non-empty and unique names. It validates commands and their SubCommands in
 // the same manner recursively. Returns nil on success.
 func ValidateCommands(commands Commands) (err error) {
 	for _, command := range commands {
-		if command.Name == "" {
-			return errors.New("validation failed: a command with an empty name is defined")
-		}
-		if command.Handler == nil {
-			return fmt.Errorf("validation failed: command '%s' has no handler assigned", command.Name)
+		if err = validateCommand(commands, command); err != nil {
+			return prependCommand(err, command)
 		}
-		if command.SubCommands.Count() > 0 {
-			for _, opt := range command.Options {
-				if opt.Kind == Variadic {
-					return fmt.Errorf("validation failed: command '%s' contains a variadic option and may have no sub-commands", command.Name)
-				}
+	}
+	return nil
+}
+
+// validateCommand validates command defined in commands.
+func validateCommand(commands Commands, command *Command) (err error) {
+	if command.Name == "" {
+		return validationErrorf(nil, "validation failed: a command with an empty name is defined")
+	}
+	if command.Handler == nil {
+		return validationErrorf(nil, "validation failed: command '%s' has no handler assigned", command.Name)
+	}
+	if command.SubCommands.Count() > 0 {
+		for _, opt := range command.Options {
+			if opt.Kind == Variadic {
+				return validationErrorf(opt, "validation failed: command '%s' contains a variadic option and may have no sub-commands", command.Name)
 			}
 		}
-		for _, other := range commands {
-			if other != command && other.Name == command.Name {
-				return fmt.Errorf("validation failed: duplicate command name: %s", command.Name)
-			}
+	}
+	for _, other := range commands {
+		if other != command && other.Name == command.Name {
+			return validationErrorf(nil, "validation failed: duplicate command name: %s", command.Name)
+		}
+	}
+	for i, alias := range command.Aliases {
+		if alias == "" {
+			return validationErrorf(nil, "validation failed: command '%s' has an empty alias", command.Name)
 		}
-		if err = ValidateOptions(command.Options); err != nil {
-			return
+		if alias == command.Name || slices.Contains(command.Aliases[:i], alias) {
+			return validationErrorf(nil, "validation failed: command '%s' has a duplicate alias: %s", command.Name, alias)
 		}
-		if err = ValidateCommands(command.SubCommands); err != nil {
-			return
+		for _, other := range commands {
+			if other != command && other.HasName(alias) {
+				return validationErrorf(nil, "validation failed: command '%s' alias '%s' conflicts with command '%s'", command.Name, alias, other.Name)
+			}
 		}
 	}
+	if err = ValidateOptions(command.Options); err != nil {
+		return
+	}
+	if err = validateConstraints(command.Constraints, command.Options); err != nil {
+		return
+	}
+	if err = ValidateCommands(command.SubCommands); err != nil {
+		return
+	}
 	return nil
 }
 
-// validateCommandExclusivityGroups calls validateExclusivityGroups for command.
-func validateCommandExclusivityGroups(command *Command) (err error) {
-	if err = validateExclusivityGroups(command.ExclusivityGroups, command.Options); err != nil {
-		return fmt.Errorf("command '%s' %w", command.Name, err)
-	}
-	return
+// validateCommandExclusivityGroups calls validateExclusivityGroups for the
+// last command in the chain of commands.
+func validateCommandExclusivityGroups(config *Config, commands []*Command) error {
+	var command = commands[len(commands)-1]
+	return validateExclusivityGroups(config, command.ExclusivityGroups, command.Options, commands)
 }
 
 // validateCommandExclusivityGroups returns nil if parsed options do not satisfy
 // any of the defined groups or an error otherwise.
-func validateExclusivityGroups(groups ExclusivityGroups, options Options) error {
-	var conflict string
+//
+// Names in a group may be long names or long aliases. Multiple names of the
+// same Option in a group do not conflict. If any Option in a group was given
+// in arguments Options read from environment are ignored. Options are defined
+// in the chain of commands. Violations are collected by config if it collects
+// errors.
+func validateExclusivityGroups(config *Config, groups ExclusivityGroups, options Options, commands []*Command) error {
+	var (
+		conflict  *Option
+		name      string
+		arguments bool
+	)
 	for _, group := range groups {
-		conflict = ""
-		for _, name := range group {
-			if options.Parsed(name) {
-				if conflict != "" {
-					return fmt.Errorf("options '%s' and '%s' are mutually exclusive", conflict, name)
+		conflict, name = nil, ""
+		arguments = givenInArguments(options, group)
+		for _, current := range group {
+			var option = options.FindLong(current)
+			if !isGiven(option, arguments) || option == conflict {
+				continue
+			}
+			if conflict != nil {
+				var err = fmt.Errorf("options '%s' and '%s' are mutually exclusive", name, current)
+				if len(commands) > 0 {
+					err = fmt.Errorf("command '%s' %w", commands[len(commands)-1].Name, err)
+				}
+				if err = config.collect(optionError(ErrExclusive, option, commands, err)); err != nil {
+					return err
 				}
-				conflict = name
+				break
 			}
+			conflict, name = option, current
 		}
 	}
 	return nil
@@ -0,0 +1,195 @@
+// Copyright 2023-2024 Vedran Vuk. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package cmdline
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"math"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Validator validates raw values of an [Option]. See [Option.Validators].
+type Validator interface {
+	// Validate must return an error describing why value is not valid or
+	// nil if it is.
+	Validate(value string) error
+	// String must return a short description of valid values which is
+	// printed in help output, e.g. 'between 1 and 10'.
+	String() string
+}
+
+// validatorFunc is a [Validator] that validates using a function.
+type validatorFunc struct {
+	description string
+	validate    func(string) error
+}
+
+// Validate implements [Validator.Validate].
+func (self *validatorFunc) Validate(value string) error { return self.validate(value) }
+
+// String implements [Validator.String].
+func (self *validatorFunc) String() string { return self.description }
+
+// ValidatorFunc returns a [Validator] described by description that
+// validates values using validate.
+func ValidatorFunc(description string, validate func(value string) error) Validator {
+	return &validatorFunc{description, validate}
+}
+
+// Range returns a [Validator] that accepts numbers between minimum and
+// maximum, inclusive. Integers may be given with a '0x', '0o' or '0b' prefix.
+// NaN and infinite values are rejected.
+func Range(minimum, maximum float64) Validator {
+	var description = fmt.Sprintf("between %v and %v", minimum, maximum)
+	return ValidatorFunc(description, func(value string) error {
+		var number, err = strconv.ParseFloat(value, 64)
+		if err != nil {
+			var i int64
+			if i, err = strconv.ParseInt(value, intBase(value), 64); err != nil {
+				return errors.New("must be a number")
+			}
+			number = float64(i)
+		}
+		if math.IsNaN(number) || math.IsInf(number, 0) {
+			return errors.New("must be a finite number")
+		}
+		if number < minimum || number > maximum {
+			return errors.New("must be " + description)
+		}
+		return nil
+	})
+}
+
+// Length returns a [Validator] that accepts values at least minimum and at
+// most maximum characters long. If maximum is less than or equal to zero the
+// length is not limited.
+func Length(minimum, maximum int) Validator {
+	var description = fmt.Sprintf("%d to %d characters", minimum, maximum)
+	if maximum <= 0 {
+		description = fmt.Sprintf("at least %d characters", minimum)
+	}
+	return ValidatorFunc(description, func(value string) error {
+		if n := utf8.RuneCountInString(value); n < minimum || (maximum > 0 && n > maximum) {
+			return errors.New("must be " + description + " long")
+		}
+		return nil
+	})
+}
+
+// Matches returns a [Validator] that accepts values matching regular
+// expression pattern. It panics if pattern does not compile.
+func Matches(pattern string) Validator {
+	var re = regexp.MustCompile(pattern)
+	var description = "matching " + pattern
+	return ValidatorFunc(description, func(value string) error {
+		if !re.MatchString(value) {
+			return errors.New("must be " + description)
+		}
+		return nil
+	})
+}
+
+// ExistingFile returns a [Validator] that accepts paths of existing files
+// which are not directories.
+func ExistingFile() Validator {
+	return ValidatorFunc("existing file", func(value string) error {
+		var info, err = os.Stat(value)
+		if err != nil {
+			return errors.New("file does not exist")
+		}
+		if info.IsDir() {
+			return errors.New("must be a file, not a directory")
+		}
+		return nil
+	})
+}
+
+// ExistingDir returns a [Validator] that accepts paths of existing
+// directories.
+func ExistingDir() Validator {
+	return ValidatorFunc("existing directory", func(value string) error {
+		var info, err = os.Stat(value)
+		if err != nil {
+			return errors.New("directory does not exist")
+		}
+		if !info.IsDir() {
+			return errors.New("must be a directory")
+		}
+		return nil
+	})
+}
+
+// WritablePath returns a [Validator] that accepts paths of existing files
+// or directories that can be written to and paths of files that do not
+// exist but can be created in an existing writable directory.
+func WritablePath() Validator {
+	return ValidatorFunc("writable path", func(value string) error {
+		var info, err = os.Stat(value)
+		switch {
+		case err == nil && info.IsDir():
+			return checkWritableDir(value)
+		case err == nil:
+			var file *os.File
+			if file, err = os.OpenFile(value, os.O_WRONLY, 0); err != nil {
+				return errors.New("file is not writable")
+			}
+			return file.Close()
+		case errors.Is(err, fs.ErrNotExist):
+			return checkWritableDir(filepath.Dir(value))
+		default:
+			return err
+		}
+	})
+}
+
+// checkWritableDir returns nil if a file can be created in directory dir.
+func checkWritableDir(dir string) error {
+	var file, err = os.CreateTemp(dir, ".cmdline-*")
+	if err != nil {
+		return fmt.Errorf("directory '%s' is not writable", dir)
+	}
+	file.Close()
+	return os.Remove(file.Name())
+}
+
+// URLScheme returns a [Validator] that accepts absolute URLs with one of
+// schemes, e.g. 'http' and 'https'. Schemes are case insensitive.
+func URLScheme(schemes ...string) Validator {
+	var description = "URL with scheme " + strings.Join(schemes, " or ")
+	return ValidatorFunc(description, func(value string) error {
+		var u, err = url.Parse(value)
+		if err != nil || !u.IsAbs() {
+			return errors.New("must be an absolute URL")
+		}
+		if !slices.ContainsFunc(schemes, func(scheme string) bool {
+			return strings.EqualFold(scheme, u.Scheme)
+		}) {
+			return errors.New("must be a " + description)
+		}
+		return nil
+	})
+}
+
+// validateValues returns an error if any of values of option is not valid
+// according to [Option.Validators].
+func validateValues(option *Option, values Values) error {
+	for _, value := range values {
+		for _, validator := range option.Validators {
+			if err := validator.Validate(value); err != nil {
+				return fmt.Errorf("invalid value '%s' for option '%s': %w", value, option.LongName, err)
+			}
+		}
+	}
+	return nil
+}